@@ -0,0 +1,94 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+
+package pilosa
+
+// attributeBuilderInline is the number of attributes an AttributeBuilder stores without allocating.
+const attributeBuilderInline = 8
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// AttributeBuilder builds attribute maps for SetRowAttrs and SetColumnAttrs queries
+// one attribute at a time.
+// The first few attributes are stored in an array, so setting them does not allocate;
+// Build allocates a map of the exact size, which costs the same allocations as a map literal.
+// The zero value is ready to use.
+type AttributeBuilder struct {
+	inline [attributeBuilderInline]attribute
+	count  int
+	extra  []attribute
+}
+
+// NewAttributeBuilder creates an empty AttributeBuilder.
+func NewAttributeBuilder() *AttributeBuilder {
+	return &AttributeBuilder{}
+}
+
+// Set sets the value of the attribute with the given key.
+// If the key was already set, its value is replaced.
+func (b *AttributeBuilder) Set(key string, value interface{}) *AttributeBuilder {
+	for i := 0; i < b.count && i < attributeBuilderInline; i++ {
+		if b.inline[i].key == key {
+			b.inline[i].value = value
+			return b
+		}
+	}
+	for i := range b.extra {
+		if b.extra[i].key == key {
+			b.extra[i].value = value
+			return b
+		}
+	}
+	if b.count < attributeBuilderInline {
+		b.inline[b.count] = attribute{key: key, value: value}
+	} else {
+		b.extra = append(b.extra, attribute{key: key, value: value})
+	}
+	b.count++
+	return b
+}
+
+// Build returns a new map with the attributes set so far.
+func (b *AttributeBuilder) Build() map[string]interface{} {
+	attrs := make(map[string]interface{}, b.count)
+	for i := 0; i < b.count && i < attributeBuilderInline; i++ {
+		attrs[b.inline[i].key] = b.inline[i].value
+	}
+	for _, attr := range b.extra {
+		attrs[attr.key] = attr.value
+	}
+	return attrs
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+
+package pilosa
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAttributeBuilder(t *testing.T) {
+	attrs := NewAttributeBuilder().
+		Set("name", "foo").
+		Set("active", true).
+		Set("size", 5).
+		Set("name", "bar").
+		Build()
+	target := map[string]interface{}{"name": "bar", "active": true, "size": 5}
+	if !reflect.DeepEqual(target, attrs) {
+		t.Fatalf("%v != %v", target, attrs)
+	}
+	comparePQL(t,
+		"SetRowAttrs(row=1, field='sample-field', active=true, name=\"bar\", size=5)",
+		sampleField.SetRowAttrs(1, attrs))
+
+	var b AttributeBuilder
+	if len(b.Build()) != 0 {
+		t.Fatalf("zero value should build an empty map")
+	}
+}
+
+func TestAttributeBuilderMany(t *testing.T) {
+	b := NewAttributeBuilder()
+	target := map[string]interface{}{}
+	for i := 0; i < 3*attributeBuilderInline; i++ {
+		key := fmt.Sprintf("attr%d", i)
+		b.Set(key, i)
+		target[key] = i
+	}
+	b.Set("attr1", "inline")
+	b.Set(fmt.Sprintf("attr%d", 2*attributeBuilderInline), "extra")
+	target["attr1"] = "inline"
+	target[fmt.Sprintf("attr%d", 2*attributeBuilderInline)] = "extra"
+	attrs := b.Build()
+	if !reflect.DeepEqual(target, attrs) {
+		t.Fatalf("%v != %v", target, attrs)
+	}
+	attrs["new"] = 1
+	if _, ok := b.Build()["new"]; ok {
+		t.Fatalf("Build should return a new map")
+	}
+}
+
+// benchmarkAttrs keeps the benchmarked maps from being optimized away.
+var benchmarkAttrs map[string]interface{}
+
+func BenchmarkAttributeBuilder(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var builder AttributeBuilder
+		attrs := builder.
+			Set("name", "foo").
+			Set("active", true).
+			Set("size", 5).
+			Set("ratio", 0.5).
+			Set("owner", "bar").
+			Build()
+		benchmarkAttrs = attrs
+	}
+}
+
+func BenchmarkAttributeMap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		attrs := map[string]interface{}{
+			"name":   "foo",
+			"active": true,
+			"size":   5,
+			"ratio":  0.5,
+			"owner":  "bar",
+		}
+		benchmarkAttrs = attrs
+	}
+}
@@ -150,6 +150,9 @@ func (c *Client) Query(query PQLQuery, options ...interface{}) (*QueryResponse,
 	if err != nil {
 		return nil, errors.Wrap(err, "making request data")
 	}
+	if query.Index() == nil {
+		return nil, ErrNoIndex
+	}
 	path := fmt.Sprintf("/index/%s/query", query.Index().name)
 	_, buf, err := c.httpRequest("POST", path, data, defaultProtobufHeaders())
 	if err != nil {
@@ -170,6 +173,9 @@ func (c *Client) Query(query PQLQuery, options ...interface{}) (*QueryResponse,
 // CreateIndex creates an index on the server using the given Index struct.
 func (c *Client) CreateIndex(index *Index) error {
 	data := []byte("")
+	if index.options != (IndexOptions{}) {
+		data = []byte(index.options.String())
+	}
 	path := fmt.Sprintf("/index/%s", index.name)
 	response, _, err := c.httpRequest("POST", path, data, nil)
 	if err != nil {
@@ -245,7 +251,7 @@ func (c *Client) syncSchema(schema *Schema, serverSchema *Schema) error {
 	var err error
 
 	// find out local - remote schema
-	diffSchema := schema.diff(serverSchema)
+	diffSchema := schema.DiffSchema(serverSchema)
 	// create the indexes and fields which doesn't exist on the server side
 	for indexName, index := range diffSchema.indexes {
 		if _, ok := serverSchema.indexes[indexName]; !ok {
@@ -263,7 +269,7 @@ func (c *Client) syncSchema(schema *Schema, serverSchema *Schema) error {
 	}
 
 	// find out remote - local schema
-	diffSchema = serverSchema.diff(schema)
+	diffSchema = serverSchema.DiffSchema(schema)
 	for indexName, index := range diffSchema.indexes {
 		if localIndex, ok := schema.indexes[indexName]; !ok {
 			schema.indexes[indexName] = index
@@ -286,19 +292,23 @@ func (c *Client) Schema() (*Schema, error) {
 	}
 	schema := NewSchema()
 	for _, indexInfo := range indexes {
-		index, err := schema.Index(indexInfo.Name)
+		index, err := schema.Index(indexInfo.Name,
+			OptIndexKeys(indexInfo.Options.Keys),
+			OptIndexTrackExistence(indexInfo.Options.TrackExistence))
 		if err != nil {
 			return nil, err
 		}
 		for _, fieldInfo := range indexInfo.Fields {
 			fieldOptions := &FieldOptions{
-				fieldType:   fieldInfo.Options.FieldType,
-				cacheSize:   int(fieldInfo.Options.CacheSize),
-				cacheType:   CacheType(fieldInfo.Options.CacheType),
-				timeQuantum: TimeQuantum(fieldInfo.Options.TimeQuantum),
-				min:         fieldInfo.Options.Min,
-				max:         fieldInfo.Options.Max,
+				fieldType:      fieldInfo.Options.FieldType,
+				cacheSize:      int(fieldInfo.Options.CacheSize),
+				cacheType:      CacheType(fieldInfo.Options.CacheType),
+				timeQuantum:    TimeQuantum(fieldInfo.Options.TimeQuantum),
+				min:            fieldInfo.Options.Min,
+				max:            fieldInfo.Options.Max,
+				noStandardView: fieldInfo.Options.NoStandardView,
 			}
+			fieldOptions.markPresent()
 			_, err := index.Field(fieldInfo.Name, fieldOptions)
 			if err != nil {
 				return nil, err
@@ -311,7 +321,7 @@ func (c *Client) Schema() (*Schema, error) {
 // ImportField imports records from the given iterator.
 func (c *Client) ImportField(field *Field, iterator RecordIterator, options ...ImportOption) error {
 	importOptions := &ImportOptions{}
-	if field.options != nil && field.options.fieldType == FieldTypeInt {
+	if field.options != nil && field.options.fieldType.IsInteger() {
 		importRecordsFunction(c.importValues)(importOptions)
 	} else {
 		importRecordsFunction(c.importBits)(importOptions)
@@ -972,12 +982,15 @@ type StatusField struct {
 
 // StatusOptions contains options for a field or an index.
 type StatusOptions struct {
-	FieldType   FieldType `json:"type"`
-	CacheType   string    `json:"cacheType"`
-	CacheSize   uint      `json:"cacheSize"`
-	TimeQuantum string    `json:"timeQuantum"`
-	Min         int64     `json:"min"`
-	Max         int64     `json:"max"`
+	FieldType      FieldType `json:"type"`
+	CacheType      string    `json:"cacheType"`
+	CacheSize      uint      `json:"cacheSize"`
+	TimeQuantum    string    `json:"timeQuantum"`
+	Min            int64     `json:"min"`
+	Max            int64     `json:"max"`
+	NoStandardView bool      `json:"noStandardView"`
+	Keys           bool      `json:"keys"`
+	TrackExistence bool      `json:"trackExistence"`
 }
 
 type exportReader struct {
@@ -35,6 +35,9 @@ package pilosa
 import (
 	"crypto/tls"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -57,6 +60,51 @@ func TestQueryWithError(t *testing.T) {
 	}
 }
 
+func TestSchemaFieldWithoutOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"indexes":[{"name":"schema-index","fields":[
+			{"name":"stargazer","options":{"type":"set","cacheType":"ranked","cacheSize":50000}},
+			{"name":"price","options":{"type":"int","min":0,"max":100}}]}]}`)
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := client.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := schema.Index("schema-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := index.Field("stargazer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.options.cacheType != CacheTypeRanked || field.options.cacheSize != 50000 {
+		t.Fatalf("the field loaded from the server should be returned: %v", field.options)
+	}
+	if _, err := index.SetField("stargazer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.IntField("price", 0, 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.IntField("price", 0, 1000); err == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestQueryNoop(t *testing.T) {
+	client := DefaultClient()
+	_, err := client.Query(Noop)
+	if err != ErrNoIndex {
+		t.Fatalf("Should have failed with ErrNoIndex: %v", err)
+	}
+}
+
 func TestClientOptions(t *testing.T) {
 	targets := []*ClientOptions{
 		{SocketTimeout: 10},
@@ -78,6 +78,9 @@ Usage:
 	// Act on the result
 	fmt.Println(response.Result())
 
+Queries are serialized to PQL with field arguments, e.g. Bitmap(row=5, field='stargazer').
+Servers which only support the frame argument of older PQL versions are not supported.
+
 See also https://www.pilosa.com/docs/api-reference/ and https://www.pilosa.com/docs/query-language/.
 */
 package pilosa
@@ -34,6 +34,8 @@ package pilosa
 
 import (
 	"fmt"
+
+	"github.com/pkg/errors"
 )
 
 // Error contains a Pilosa specific error.
@@ -55,16 +57,68 @@ var (
 	ErrEmptyCluster           = NewError("No usable addresses in the cluster")
 	ErrIndexExists            = NewError("Index exists")
 	ErrFieldExists            = NewError("Field exists")
+	ErrFieldNotFound          = NewError("Field not found")
+	ErrFieldOptionsConflict   = NewError("Field exists with different options")
 	ErrInvalidIndexName       = NewError("Invalid index name")
 	ErrInvalidFieldName       = NewError("Invalid field name")
+	ErrNameTooLong            = NewError("Name too long")
 	ErrInvalidLabel           = NewError("Invalid label")
 	ErrInvalidKey             = NewError("Invalid key")
 	ErrTriedMaxHosts          = NewError("Tried max hosts, still failing")
 	ErrAddrURIClusterExpected = NewError("Addresses, URIs or a cluster is expected")
 	ErrInvalidQueryOption     = NewError("Invalid query option")
+	ErrInvalidSchemaOption    = NewError("Invalid schema option")
 	ErrInvalidIndexOption     = NewError("Invalid index option")
 	ErrInvalidFieldOption     = NewError("Invalid field option")
 	ErrNoFragmentNodes        = NewError("No fragment nodes")
 	ErrNoSlice                = NewError("Index has no slices")
 	ErrUnknownType            = NewError("Unknown type")
+	ErrNoIndex                = NewError("Query has no index")
+	ErrIndexMismatch          = NewError("Index mismatch")
+	ErrNoTimeQuantum          = NewError("No time quantum")
+	ErrInvalidPQL             = NewError("Invalid PQL")
 )
+
+// queryError is the error of a row or base query.
+// It adds the query type and the index name to the error which occurred while building the query.
+// The original error is returned by Cause, for errors.Cause.
+// Unwrap returns the root cause of the original error, so errors.Is and errors.As of Go 1.13
+// find the predefined errors even with versions of github.com/pkg/errors whose
+// wrappers do not have an Unwrap method.
+type queryError struct {
+	queryType PQLQueryType
+	indexName string
+	err       error
+}
+
+// wrapQueryError wraps err in a queryError, unless err is nil or already a queryError.
+func wrapQueryError(queryType PQLQueryType, index *Index, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*queryError); ok {
+		return err
+	}
+	qe := &queryError{queryType: queryType, err: err}
+	if index != nil {
+		qe.indexName = index.name
+	}
+	return qe
+}
+
+func (e *queryError) Error() string {
+	if e.indexName == "" {
+		return fmt.Sprintf("building %s query: %s", e.queryType, e.err)
+	}
+	return fmt.Sprintf("building %s query for index %s: %s", e.queryType, e.indexName, e.err)
+}
+
+// Cause returns the original error.
+func (e *queryError) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the root cause of the original error.
+func (e *queryError) Unwrap() error {
+	return errors.Cause(e.err)
+}
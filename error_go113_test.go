@@ -0,0 +1,69 @@
+// +build go1.13
+
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+package pilosa
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQueryErrorIs(t *testing.T) {
+	err := sampleIndex.SetBit("no-such-field", 1, 2).Error()
+	if !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("errors.Is should find ErrFieldNotFound in %v", err)
+	}
+	if errors.Is(err, ErrFieldExists) {
+		t.Fatalf("errors.Is should not find ErrFieldExists in %v", err)
+	}
+	err = sampleIndex.Xor(b1).Error()
+	if errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("errors.Is should not find ErrFieldNotFound in %v", err)
+	}
+}
+
+func TestQueryErrorAs(t *testing.T) {
+	err := sampleIndex.SetBit("no-such-field", 1, 2).Error()
+	var pilosaErr *Error
+	if !errors.As(err, &pilosaErr) {
+		t.Fatalf("errors.As should find an *Error in %v", err)
+	}
+	if pilosaErr != ErrFieldNotFound {
+		t.Fatalf("errors.As should find ErrFieldNotFound, got %v", pilosaErr)
+	}
+	var qe *queryError
+	if !errors.As(err, &qe) || qe.queryType != QueryTypeBase {
+		t.Fatalf("errors.As should find the queryError in %v", err)
+	}
+}
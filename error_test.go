@@ -35,6 +35,8 @@ package pilosa
 import (
 	"fmt"
 	"testing"
+
+	"github.com/pkg/errors"
 )
 
 func TestError(t *testing.T) {
@@ -44,3 +46,33 @@ func TestError(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestQueryError(t *testing.T) {
+	q := sampleIndex.SetBit("no-such-field", 1, 2)
+	err := q.Error()
+	target := "building base query for index sample-index: field no-such-field in index sample-index: Error: Field not found"
+	if err.Error() != target {
+		t.Fatalf("%s != %s", target, err.Error())
+	}
+	if errors.Cause(err) != ErrFieldNotFound {
+		t.Fatalf("cause should be ErrFieldNotFound, got %v", errors.Cause(err))
+	}
+	unwrapper, ok := err.(interface {
+		Unwrap() error
+	})
+	if !ok || errors.Cause(unwrapper.Unwrap()) != ErrFieldNotFound {
+		t.Fatalf("Unwrap should return the original error")
+	}
+
+	row := sampleIndex.Union(sampleIndex.Xor(b1))
+	target = "building row query for index sample-index: Error: Xor operation requires at least 2 rows"
+	if row.Error().Error() != target {
+		t.Fatalf("error should be wrapped once: %s", row.Error())
+	}
+	if NewPQLRowQuery("", nil, ErrNoIndex).Error().Error() != "building row query: Error: Query has no index" {
+		t.Fatalf("unexpected error for nil index: %s", NewPQLRowQuery("", nil, ErrNoIndex).Error())
+	}
+	if NewPQLBaseQuery("", sampleIndex, nil).Error() != nil {
+		t.Fatalf("nil error should not be wrapped")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+
+package pilosa
+
+// FieldMutator queues mutating queries for a field and builds them into a batch query.
+// Use Field.Mutator, Field.QueueSetBit or Field.QueueClearBit to create an instance.
+type FieldMutator struct {
+	field *Field
+	batch *PQLBatchQuery
+}
+
+// Mutator creates an empty FieldMutator for this field.
+func (f *Field) Mutator() *FieldMutator {
+	return &FieldMutator{
+		field: f,
+		batch: f.index.BatchQuery(),
+	}
+}
+
+// QueueSetBit creates a FieldMutator with a queued SetBit query.
+func (f *Field) QueueSetBit(rowID uint64, columnID uint64) *FieldMutator {
+	return f.Mutator().QueueSetBit(rowID, columnID)
+}
+
+// QueueClearBit creates a FieldMutator with a queued ClearBit query.
+func (f *Field) QueueClearBit(rowID uint64, columnID uint64) *FieldMutator {
+	return f.Mutator().QueueClearBit(rowID, columnID)
+}
+
+// QueueSetBit queues a SetBit query.
+func (m *FieldMutator) QueueSetBit(rowID uint64, columnID uint64) *FieldMutator {
+	m.batch.Add(m.field.SetBit(rowID, columnID))
+	return m
+}
+
+// QueueSetBitK queues a SetBit query using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (m *FieldMutator) QueueSetBitK(rowKey string, columnKey string) *FieldMutator {
+	m.batch.Add(m.field.SetBitK(rowKey, columnKey))
+	return m
+}
+
+// QueueClearBit queues a ClearBit query.
+func (m *FieldMutator) QueueClearBit(rowID uint64, columnID uint64) *FieldMutator {
+	m.batch.Add(m.field.ClearBit(rowID, columnID))
+	return m
+}
+
+// QueueClearBitK queues a ClearBit query using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (m *FieldMutator) QueueClearBitK(rowKey string, columnKey string) *FieldMutator {
+	m.batch.Add(m.field.ClearBitK(rowKey, columnKey))
+	return m
+}
+
+// QueueSetIntValue queues a SetValue query.
+func (m *FieldMutator) QueueSetIntValue(columnID uint64, value int64) *FieldMutator {
+	m.batch.Add(m.field.SetIntValue(columnID, value))
+	return m
+}
+
+// QueueSetRowAttrs queues a SetRowAttrs query.
+func (m *FieldMutator) QueueSetRowAttrs(rowID uint64, attrs map[string]interface{}) *FieldMutator {
+	m.batch.Add(m.field.SetRowAttrs(rowID, attrs))
+	return m
+}
+
+// Build returns a batch query with the queued queries.
+// If any of the queued queries has an error, the batch query has that error.
+// The mutator can be used after Build, queued queries are not affected.
+func (m *FieldMutator) Build() *PQLBatchQuery {
+	return &PQLBatchQuery{
+		index:   m.batch.index,
+		queries: m.batch.Queries(),
+		err:     m.batch.err,
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+
+package pilosa
+
+import "testing"
+
+func TestFieldMutator(t *testing.T) {
+	comparePQL(t,
+		"SetBit(row=1, field='sample-field', col=2)SetBit(row=1, field='sample-field', col=3)",
+		sampleField.QueueSetBit(1, 2).QueueSetBit(1, 3).Build())
+	comparePQL(t,
+		"ClearBit(row=1, field='sample-field', col=2)SetBit(row='a', field='sample-field', col='b')ClearBit(row='a', field='sample-field', col='b')",
+		sampleField.QueueClearBit(1, 2).QueueSetBitK("a", "b").QueueClearBitK("a", "b").Build())
+	comparePQL(t,
+		"SetValue(col=5, sample-field=10)SetRowAttrs(row=1, field='sample-field', active=true)",
+		sampleField.Mutator().QueueSetIntValue(5, 10).QueueSetRowAttrs(1, map[string]interface{}{"active": true}).Build())
+	comparePQL(t, "", sampleField.Mutator().Build())
+}
+
+func TestFieldMutatorBuildCopies(t *testing.T) {
+	m := sampleField.QueueSetBit(1, 2)
+	q := m.Build()
+	m.QueueSetBit(1, 3)
+	comparePQL(t, "SetBit(row=1, field='sample-field', col=2)", q)
+	if m.Build().Count() != 2 {
+		t.Fatalf("mutator should have 2 queries")
+	}
+}
+
+func TestFieldMutatorError(t *testing.T) {
+	q := sampleField.QueueSetBit(1, 2).QueueSetRowAttrs(1, map[string]interface{}{"color?": 1}).Build()
+	if q.Error() == nil {
+		t.Fatalf("should have failed")
+	}
+}
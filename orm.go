@@ -33,11 +33,17 @@
 package pilosa
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const timeFormat = "2006-01-02T15:04"
@@ -58,12 +64,52 @@ func NewSchema() *Schema {
 	}
 }
 
+// SchemaOption is used to pass an option to the NewSchemaWith function.
+type SchemaOption func(schema *Schema) error
+
+// NewSchemaWith creates a new Schema with the given options.
+// Options are applied in order.
+func NewSchemaWith(options ...SchemaOption) (*Schema, error) {
+	schema := NewSchema()
+	for _, option := range options {
+		if option == nil {
+			return nil, ErrInvalidSchemaOption
+		}
+		if err := option(schema); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// OptSchemaIndex is a schema option which adds an index with the given name and options.
+// If the index was already added, the options are ignored.
+func OptSchemaIndex(name string, options ...IndexOption) SchemaOption {
+	return func(schema *Schema) error {
+		_, err := schema.Index(name, options...)
+		return err
+	}
+}
+
+// OptSchemaExistingIndex is a schema option which adds a deep copy of the given index.
+// Returns ErrIndexExists if an index with the same name was already added.
+func OptSchemaExistingIndex(index *Index) SchemaOption {
+	return func(schema *Schema) error {
+		if index == nil {
+			return ErrInvalidSchemaOption
+		}
+		_, err := index.CopyInto(schema)
+		return err
+	}
+}
+
 // Index returns an index with a name.
-func (s *Schema) Index(name string) (*Index, error) {
+// The options are only used if the index does not exist in the schema yet.
+func (s *Schema) Index(name string, options ...IndexOption) (*Index, error) {
 	if index, ok := s.indexes[name]; ok {
 		return index, nil
 	}
-	index, err := NewIndex(name)
+	index, err := NewIndex(name, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -75,20 +121,45 @@ func (s *Schema) Index(name string) (*Index, error) {
 func (s *Schema) Indexes() map[string]*Index {
 	result := make(map[string]*Index)
 	for k, v := range s.indexes {
-		result[k] = v.copy()
+		result[k] = v.Copy()
 	}
 	return result
 }
 
-func (s *Schema) diff(other *Schema) *Schema {
+// IndexByName returns the index with the given name and true if it exists in the schema,
+// otherwise nil and false.
+// Unlike Indexes, the index is not copied, so changes to it affect the schema.
+func (s *Schema) IndexByName(name string) (*Index, bool) {
+	index, ok := s.indexes[name]
+	return index, ok
+}
+
+// Copy returns a deep copy of the schema.
+// Changes to the copy, such as adding indexes or fields, do not affect this schema.
+func (s *Schema) Copy() *Schema {
+	result := NewSchema()
+	for name, index := range s.indexes {
+		result.indexes[name] = index.Copy()
+	}
+	return result
+}
+
+// DiffSchema returns a schema with the indexes and fields of this schema which do not exist in the other schema.
+// An index which exists in both schemas is included only with its missing fields.
+// If other is nil, a copy of this schema is returned.
+func (s *Schema) DiffSchema(other *Schema) *Schema {
+	if other == nil {
+		return s.Copy()
+	}
 	result := NewSchema()
 	for indexName, index := range s.indexes {
 		if otherIndex, ok := other.indexes[indexName]; !ok {
 			// if the index doesn't exist in the other schema, simply copy it
-			result.indexes[indexName] = index.copy()
+			result.indexes[indexName] = index.Copy()
 		} else {
 			// the index exists in the other schema; check the fields
 			resultIndex, _ := NewIndex(indexName)
+			resultIndex.options = index.options
 			for fieldName, field := range index.fields {
 				if _, ok := otherIndex.fields[fieldName]; !ok {
 					// the field doesn't exist in the other schema, copy it
@@ -105,26 +176,193 @@ func (s *Schema) diff(other *Schema) *Schema {
 	return result
 }
 
+// SyncActionType is the type of a schema synchronization action.
+type SyncActionType string
+
+// SyncActionType constants
+const (
+	SyncActionCreate SyncActionType = "create"
+	SyncActionDelete SyncActionType = "delete"
+)
+
+// SyncAction is a change to an index or a field required to synchronize two schemas.
+// Field is empty for index actions.
+type SyncAction struct {
+	Type  SyncActionType
+	Index string
+	Field string
+}
+
+// SyncPlan returns the actions which would make the remote schema the same as this schema.
+// Indexes and fields which exist only in this schema are created,
+// indexes and fields which exist only in the remote schema are deleted.
+// Note that Client.SyncSchema never deletes indexes or fields.
+func (s *Schema) SyncPlan(remote *Schema) []SyncAction {
+	plan := syncActions(SyncActionCreate, s, remote)
+	return append(plan, syncActions(SyncActionDelete, remote, s)...)
+}
+
+// DiffKeys returns the names of the indexes and fields which differ between this schema and the remote schema.
+// Added indexes and fields exist only in this schema, removed ones exist only in the remote schema.
+// Field names are keyed by index name. Fields of an added index are included in addedFields,
+// fields of a removed index are not included in removedFields.
+// The result has the same contents as the plan returned by SyncPlan, names are sorted.
+func (s *Schema) DiffKeys(remote *Schema) (addedIndexes, removedIndexes []string, addedFields, removedFields map[string][]string) {
+	addedIndexes, removedIndexes = []string{}, []string{}
+	addedFields, removedFields = map[string][]string{}, map[string][]string{}
+	for _, action := range s.SyncPlan(remote) {
+		switch {
+		case action.Type == SyncActionCreate && action.Field == "":
+			addedIndexes = append(addedIndexes, action.Index)
+		case action.Type == SyncActionCreate:
+			addedFields[action.Index] = append(addedFields[action.Index], action.Field)
+		case action.Field == "":
+			removedIndexes = append(removedIndexes, action.Index)
+		default:
+			removedFields[action.Index] = append(removedFields[action.Index], action.Field)
+		}
+	}
+	return addedIndexes, removedIndexes, addedFields, removedFields
+}
+
+func syncActions(actionType SyncActionType, from *Schema, to *Schema) []SyncAction {
+	actions := []SyncAction{}
+	diff := from.DiffSchema(to)
+	for _, indexName := range sortedIndexNames(diff.indexes) {
+		if _, ok := to.indexes[indexName]; !ok {
+			actions = append(actions, SyncAction{Type: actionType, Index: indexName})
+			if actionType == SyncActionDelete {
+				// deleting the index deletes its fields
+				continue
+			}
+		}
+		for _, fieldName := range sortedFieldNames(diff.indexes[indexName].fields) {
+			actions = append(actions, SyncAction{Type: actionType, Index: indexName, Field: fieldName})
+		}
+	}
+	return actions
+}
+
+func sortedIndexNames(indexes map[string]*Index) []string {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedFieldNames(fields map[string]*Field) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatPlan returns a human readable representation of the given plan.
+func (s *Schema) FormatPlan(plan []SyncAction) string {
+	buf := &bytes.Buffer{}
+	// writing to a bytes.Buffer never fails
+	s.WritePlan(buf, plan, false)
+	return buf.String()
+}
+
+// WritePlan writes a human readable representation of the given plan to w, one action per line.
+// Creations are prefixed with +, deletions are prefixed with -.
+// If colored is true, lines are colored using ANSI escape codes.
+func (s *Schema) WritePlan(w io.Writer, plan []SyncAction, colored bool) error {
+	for _, action := range plan {
+		sign, color := "+", "\x1b[32m"
+		if action.Type == SyncActionDelete {
+			sign, color = "-", "\x1b[31m"
+		}
+		line := fmt.Sprintf("%s %s index %s", sign, action.Type, action.Index)
+		if action.Field != "" {
+			line = fmt.Sprintf("%s %s field %s/%s", sign, action.Type, action.Index, action.Field)
+		}
+		if colored {
+			line = fmt.Sprintf("%s%s\x1b[0m", color, line)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PQLQueryType is the kind of a PQL query.
+type PQLQueryType string
+
+// PQL query types.
+const (
+	QueryTypeRow   PQLQueryType = "row"
+	QueryTypeBase  PQLQueryType = "base"
+	QueryTypeBatch PQLQueryType = "batch"
+	QueryTypeNoop  PQLQueryType = "noop"
+)
+
 // PQLQuery is an interface for PQL queries.
+// The PQL of a query is built when the query is created, so later changes to the
+// arguments used to create it, such as attribute maps or slices, do not affect it.
 type PQLQuery interface {
 	Index() *Index
+	QueryType() PQLQueryType
 	serialize() string
 	Error() error
 }
 
+// NoopQuery is a query which does nothing.
+// It serializes to an empty string, so adding it to a batch query does not change the PQL of the batch.
+// The batch still keeps the empty query, e.g., it is counted by Count and returned by Queries;
+// use PQLBatchQuery.Compact to remove empty queries.
+// Use the Noop variable instead of creating instances.
+type NoopQuery struct{}
+
+// Noop is the no-op query.
+var Noop PQLQuery = &NoopQuery{}
+
+// Index returns nil, since a no-op query does not belong to an index.
+func (q *NoopQuery) Index() *Index {
+	return nil
+}
+
+// QueryType returns QueryTypeNoop.
+func (q *NoopQuery) QueryType() PQLQueryType {
+	return QueryTypeNoop
+}
+
+// String returns the PQL for this query, which is always empty.
+func (q *NoopQuery) String() string {
+	return q.serialize()
+}
+
+func (q *NoopQuery) serialize() string {
+	return ""
+}
+
+// Error returns nil.
+func (q *NoopQuery) Error() error {
+	return nil
+}
+
 // PQLBaseQuery is the base implementation for PQLQuery.
 type PQLBaseQuery struct {
-	index *Index
-	pql   string
-	err   error
+	index  *Index
+	pql    string
+	err    error
+	source string
 }
 
 // NewPQLBaseQuery creates a new PQLQuery with the given PQL and index.
+// A non-nil err is wrapped with the query type and the index name, use errors.Cause to get err back.
 func NewPQLBaseQuery(pql string, index *Index, err error) *PQLBaseQuery {
 	return &PQLBaseQuery{
-		index: index,
-		pql:   pql,
-		err:   err,
+		index:  index,
+		pql:    pql,
+		err:    wrapQueryError(QueryTypeBase, index, err),
+		source: querySource(),
 	}
 }
 
@@ -133,6 +371,16 @@ func (q *PQLBaseQuery) Index() *Index {
 	return q.index
 }
 
+// String returns the PQL for this query.
+func (q *PQLBaseQuery) String() string {
+	return q.serialize()
+}
+
+// QueryType returns QueryTypeBase.
+func (q *PQLBaseQuery) QueryType() PQLQueryType {
+	return QueryTypeBase
+}
+
 func (q *PQLBaseQuery) serialize() string {
 	return q.pql
 }
@@ -142,11 +390,23 @@ func (q PQLBaseQuery) Error() error {
 	return q.err
 }
 
+// Source returns the location of the code which created this query in file.go:line format.
+// It is only available if the client was built with the pilosadebug build tag, otherwise it is empty.
+func (q *PQLBaseQuery) Source() string {
+	return q.source
+}
+
+// ByteSize returns the length of the PQL for this query in bytes.
+func (q *PQLBaseQuery) ByteSize() int {
+	return len(q.pql)
+}
+
 // PQLRowQuery is the return type for row queries.
 type PQLRowQuery struct {
-	index *Index
-	pql   string
-	err   error
+	index  *Index
+	pql    string
+	err    error
+	source string
 }
 
 // Index returns the index for this query/
@@ -154,6 +414,16 @@ func (q *PQLRowQuery) Index() *Index {
 	return q.index
 }
 
+// String returns the PQL for this query.
+func (q *PQLRowQuery) String() string {
+	return q.serialize()
+}
+
+// QueryType returns QueryTypeRow.
+func (q *PQLRowQuery) QueryType() PQLQueryType {
+	return QueryTypeRow
+}
+
 func (q *PQLRowQuery) serialize() string {
 	return q.pql
 }
@@ -163,6 +433,52 @@ func (q PQLRowQuery) Error() error {
 	return q.err
 }
 
+// Source returns the location of the code which created this query in file.go:line format.
+// It is only available if the client was built with the pilosadebug build tag, otherwise it is empty.
+func (q *PQLRowQuery) Source() string {
+	return q.source
+}
+
+// ByteSize returns the length of the PQL for this query in bytes.
+func (q *PQLRowQuery) ByteSize() int {
+	return len(q.pql)
+}
+
+// Negate creates a Not query for this row.
+// It is a shorthand for q.Index().Not(q).
+func (q *PQLRowQuery) Negate() *PQLRowQuery {
+	if q == nil {
+		return NewPQLRowQuery("", nil, NewError("Negate requires a row"))
+	}
+	return q.index.Not(q)
+}
+
+// Count creates a Count query for this row.
+// It is a shorthand for q.Index().Count(q).
+func (q *PQLRowQuery) Count() *PQLBaseQuery {
+	return q.index.Count(q)
+}
+
+// TopN creates a TopN query for the given field with the given item count,
+// which counts only the columns in this row.
+// It is a shorthand for field.TopNFiltered(n, q).
+func (q *PQLRowQuery) TopN(field *Field, n uint64) *PQLRowQuery {
+	if field == nil {
+		return NewPQLRowQuery("", q.index, NewError("TopN requires a field"))
+	}
+	return field.TopNFiltered(n, q)
+}
+
+// Sum creates a Sum query for the values of aggField in this row.
+// It is a shorthand for aggField.Sum(q).
+// aggField must belong to the index of this row, otherwise the query has an error.
+func (q *PQLRowQuery) Sum(aggField *Field) *PQLBaseQuery {
+	if aggField == nil || !sameIndex(aggField.index, q.index) {
+		return NewPQLBaseQuery("", q.index, NewError("Sum requires a field of the index of the row"))
+	}
+	return aggField.Sum(q)
+}
+
 // PQLBatchQuery contains a batch of PQL queries.
 // Use Index.BatchQuery function to create an instance.
 //
@@ -185,7 +501,18 @@ func (q *PQLBatchQuery) Index() *Index {
 	return q.index
 }
 
+// String returns the PQL for this query.
+func (q *PQLBatchQuery) String() string {
+	return q.serialize()
+}
+
+// QueryType returns QueryTypeBatch.
+func (q *PQLBatchQuery) QueryType() PQLQueryType {
+	return QueryTypeBatch
+}
+
 func (q *PQLBatchQuery) serialize() string {
+	// strings.Join computes the total length first, so the result is built with a single allocation.
 	return strings.Join(q.queries, "")
 }
 
@@ -202,34 +529,349 @@ func (q *PQLBatchQuery) Add(query PQLQuery) {
 	q.queries = append(q.queries, query.serialize())
 }
 
+// Merge appends the queries of the other batch to this batch.
+// Like Add, if the other batch has an error, this batch has that error afterwards.
+// Returns ErrIndexMismatch without changing this batch if the batches belong to indexes with different names.
+func (q *PQLBatchQuery) Merge(other *PQLBatchQuery) error {
+	if other == nil {
+		return nil
+	}
+	if !sameIndex(other.index, q.index) {
+		return ErrIndexMismatch
+	}
+	if other.err != nil {
+		q.err = other.err
+	}
+	q.queries = append(q.queries, other.queries...)
+	return nil
+}
+
+// Queries returns a copy of the PQL queries in the batch.
+func (q *PQLBatchQuery) Queries() []string {
+	queries := make([]string, len(q.queries))
+	copy(queries, q.queries)
+	return queries
+}
+
+// Count returns the number of queries in the batch.
+func (q *PQLBatchQuery) Count() int {
+	return len(q.queries)
+}
+
+// EstimatedByteSize returns the length of the PQL for this batch in bytes, without serializing it.
+func (q *PQLBatchQuery) EstimatedByteSize() int {
+	size := 0
+	for _, query := range q.queries {
+		size += len(query)
+	}
+	return size
+}
+
+// Reset removes all queries and the error from the batch, so it can be reused.
+// The index of the batch does not change.
+func (q *PQLBatchQuery) Reset() {
+	q.queries = q.queries[:0]
+	q.err = nil
+}
+
+// Deduplicate returns a new batch which contains the queries of this batch
+// without duplicates, in the order of their first occurrence.
+func (q *PQLBatchQuery) Deduplicate() *PQLBatchQuery {
+	seen := make(map[string]struct{}, len(q.queries))
+	queries := make([]string, 0, len(q.queries))
+	for _, query := range q.queries {
+		if _, ok := seen[query]; ok {
+			continue
+		}
+		seen[query] = struct{}{}
+		queries = append(queries, query)
+	}
+	return &PQLBatchQuery{
+		index:   q.index,
+		queries: queries,
+		err:     q.err,
+	}
+}
+
+// Compact returns a new batch which contains the queries of this batch
+// without empty queries, such as Noop.
+// The error of this batch, if any, is kept in the new batch, so a batch
+// which had an erroneous query added to it still fails.
+func (q *PQLBatchQuery) Compact() *PQLBatchQuery {
+	queries := make([]string, 0, len(q.queries))
+	for _, query := range q.queries {
+		if query != "" {
+			queries = append(queries, query)
+		}
+	}
+	return &PQLBatchQuery{
+		index:   q.index,
+		queries: queries,
+		err:     q.err,
+	}
+}
+
+// Reverse returns a new batch which contains the queries of this batch in reverse order.
+func (q *PQLBatchQuery) Reverse() *PQLBatchQuery {
+	queries := make([]string, len(q.queries))
+	for i, query := range q.queries {
+		queries[len(q.queries)-1-i] = query
+	}
+	return &PQLBatchQuery{
+		index:   q.index,
+		queries: queries,
+		err:     q.err,
+	}
+}
+
+// Take returns a new batch which contains the first n queries of this batch.
+// If n is greater than the number of queries, all queries are returned.
+// If n is less than 1, the new batch is empty.
+func (q *PQLBatchQuery) Take(n int) *PQLBatchQuery {
+	n = clampBatchIndex(n, len(q.queries))
+	return q.slice(0, n)
+}
+
+// Skip returns a new batch which contains the queries of this batch after the first n.
+// If n is greater than the number of queries, the new batch is empty.
+// If n is less than 1, all queries are returned.
+// Skip(n).Take(m) returns a page of m queries starting at n.
+func (q *PQLBatchQuery) Skip(n int) *PQLBatchQuery {
+	n = clampBatchIndex(n, len(q.queries))
+	return q.slice(n, len(q.queries))
+}
+
+func (q *PQLBatchQuery) slice(start int, end int) *PQLBatchQuery {
+	queries := make([]string, end-start)
+	copy(queries, q.queries[start:end])
+	return &PQLBatchQuery{
+		index:   q.index,
+		queries: queries,
+		err:     q.err,
+	}
+}
+
+func clampBatchIndex(n int, size int) int {
+	if n < 0 {
+		return 0
+	}
+	if n > size {
+		return size
+	}
+	return n
+}
+
+// QueryPlan is a list of queries which can be transformed before being turned into a batch query.
+// Methods of QueryPlan do not modify the plan they are called on.
+type QueryPlan []PQLQuery
+
+// Append returns a new plan with the query added to the end of this plan.
+func (p QueryPlan) Append(query PQLQuery) QueryPlan {
+	result := make(QueryPlan, len(p), len(p)+1)
+	copy(result, p)
+	return append(result, query)
+}
+
+// Filter returns a new plan with the queries of this plan for which keep returns true.
+func (p QueryPlan) Filter(keep func(query PQLQuery) bool) QueryPlan {
+	result := make(QueryPlan, 0, len(p))
+	for _, query := range p {
+		if keep(query) {
+			result = append(result, query)
+		}
+	}
+	return result
+}
+
+// Map returns a new plan with the result of calling transform for each query of this plan.
+func (p QueryPlan) Map(transform func(query PQLQuery) PQLQuery) QueryPlan {
+	result := make(QueryPlan, len(p))
+	for i, query := range p {
+		result[i] = transform(query)
+	}
+	return result
+}
+
+// ToBatchQuery creates a batch query for the given index with the queries of this plan.
+// If any of the queries has an error, the batch query has that error.
+func (p QueryPlan) ToBatchQuery(idx *Index) *PQLBatchQuery {
+	batch := &PQLBatchQuery{
+		index:   idx,
+		queries: make([]string, 0, len(p)),
+	}
+	for _, query := range p {
+		batch.Add(query)
+	}
+	return batch
+}
+
+// MultiIndexBatch contains batches of PQL queries for more than one index.
+// Queries are grouped by the index they belong to, so a multi-index batch
+// can be sent to the server in a single request.
+//
+// The expected request body is a JSON array with one object per index,
+// ordered by index name:
+//
+// 	[{"index":"a","query":"..."},{"index":"b","query":"..."}]
+type MultiIndexBatch struct {
+	batches map[string]*PQLBatchQuery
+}
+
+// NewMultiIndexBatch creates an empty MultiIndexBatch.
+func NewMultiIndexBatch() *MultiIndexBatch {
+	return &MultiIndexBatch{
+		batches: map[string]*PQLBatchQuery{},
+	}
+}
+
+// Add adds a query to the batch of its index.
+func (b *MultiIndexBatch) Add(query PQLQuery) {
+	index := query.Index()
+	if index == nil {
+		// queries without an index, such as Noop, have nothing to add
+		return
+	}
+	batch, ok := b.batches[index.name]
+	if !ok {
+		batch = index.BatchQuery()
+		b.batches[index.name] = batch
+	}
+	batch.Add(query)
+}
+
+// Queries returns the batch queries keyed by index name.
+func (b *MultiIndexBatch) Queries() map[string]*PQLBatchQuery {
+	result := make(map[string]*PQLBatchQuery, len(b.batches))
+	for k, v := range b.batches {
+		result[k] = v
+	}
+	return result
+}
+
+// Error returns the first error of the batches or nil.
+func (b *MultiIndexBatch) Error() error {
+	for _, name := range b.indexNames() {
+		if err := b.batches[name].Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON serializes the batch to the format expected by the server.
+func (b *MultiIndexBatch) MarshalJSON() ([]byte, error) {
+	type indexQuery struct {
+		Index string `json:"index"`
+		Query string `json:"query"`
+	}
+	names := b.indexNames()
+	queries := make([]indexQuery, 0, len(names))
+	for _, name := range names {
+		queries = append(queries, indexQuery{
+			Index: name,
+			Query: b.batches[name].serialize(),
+		})
+	}
+	return json.Marshal(queries)
+}
+
+func (b *MultiIndexBatch) indexNames() []string {
+	names := make([]string, 0, len(b.batches))
+	for name := range b.batches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // NewPQLRowQuery creates a new PqlRowQuery.
+// A non-nil err is wrapped with the query type and the index name, use errors.Cause to get err back.
 func NewPQLRowQuery(pql string, index *Index, err error) *PQLRowQuery {
 	return &PQLRowQuery{
-		index: index,
-		pql:   pql,
-		err:   err,
+		index:  index,
+		pql:    pql,
+		err:    wrapQueryError(QueryTypeRow, index, err),
+		source: querySource(),
 	}
 }
 
 // Index is a Pilosa index. The purpose of the Index is to represent a data namespace.
 // You cannot perform cross-index queries. Column-level attributes are global to the Index.
 type Index struct {
-	name   string
-	fields map[string]*Field
+	name    string
+	fields  map[string]*Field
+	options IndexOptions
 }
 
 func (idx *Index) String() string {
 	return fmt.Sprintf("%#v", idx)
 }
 
-// NewIndex creates an index with a name.
-func NewIndex(name string) (*Index, error) {
-	if err := validateIndexName(name); err != nil {
+// IndexOptions contains options to customize Index objects.
+type IndexOptions struct {
+	keys           bool
+	trackExistence bool
+}
+
+// Keys returns true if the index uses string column keys.
+func (io IndexOptions) Keys() bool {
+	return io.keys
+}
+
+// TrackExistence returns true if the index keeps track of the columns which have bits set.
+func (io IndexOptions) TrackExistence() bool {
+	return io.trackExistence
+}
+
+func (io IndexOptions) String() string {
+	mopt := map[string]interface{}{}
+	if io.keys {
+		mopt["keys"] = true
+	}
+	if io.trackExistence {
+		mopt["trackExistence"] = true
+	}
+	return fmt.Sprintf(`{"options":%s}`, encodeMap(mopt))
+}
+
+// IndexOption is used to pass an option to Schema.Index and NewIndex functions.
+type IndexOption func(options *IndexOptions) error
+
+// OptIndexKeys sets whether the index uses string column keys.
+// This will only work against a Pilosa Enterprise server.
+func OptIndexKeys(keys bool) IndexOption {
+	return func(options *IndexOptions) error {
+		options.keys = keys
+		return nil
+	}
+}
+
+// OptIndexTrackExistence sets whether the index keeps track of the columns which have bits set.
+func OptIndexTrackExistence(trackExistence bool) IndexOption {
+	return func(options *IndexOptions) error {
+		options.trackExistence = trackExistence
+		return nil
+	}
+}
+
+// NewIndex creates an index with a name and options.
+func NewIndex(name string, options ...IndexOption) (*Index, error) {
+	if err := ValidateIndexName(name); err != nil {
 		return nil, err
 	}
+	indexOptions := IndexOptions{}
+	for _, option := range options {
+		if option == nil {
+			return nil, ErrInvalidIndexOption
+		}
+		if err := option(&indexOptions); err != nil {
+			return nil, err
+		}
+	}
 	return &Index{
-		name:   name,
-		fields: map[string]*Field{},
+		name:    name,
+		fields:  map[string]*Field{},
+		options: indexOptions,
 	}, nil
 }
 
@@ -242,43 +884,128 @@ func (idx *Index) Fields() map[string]*Field {
 	return result
 }
 
-func (idx *Index) copy() *Index {
-	fields := make(map[string]*Field)
-	for name, f := range idx.fields {
-		fields[name] = f.copy()
-	}
+// Copy returns a deep copy of the index.
+// The fields of the copy belong to the copy, so changes to them do not affect this index.
+func (idx *Index) Copy() *Index {
 	index := &Index{
-		name:   idx.name,
-		fields: fields,
+		name:    idx.name,
+		fields:  make(map[string]*Field, len(idx.fields)),
+		options: idx.options,
+	}
+	for name, f := range idx.fields {
+		field := f.copy()
+		field.index = index
+		index.fields[name] = field
 	}
 	return index
 }
 
+// CopyInto adds a deep copy of the index to the given schema and returns the copy.
+// Returns ErrIndexExists if the schema already has an index with the same name.
+func (idx *Index) CopyInto(schema *Schema) (*Index, error) {
+	if _, ok := schema.indexes[idx.name]; ok {
+		return nil, ErrIndexExists
+	}
+	index := idx.Copy()
+	schema.indexes[idx.name] = index
+	return index, nil
+}
+
+// Options returns the options of this index.
+func (idx *Index) Options() IndexOptions {
+	return idx.options
+}
+
 // Name returns the name of this index.
+// The name is validated when the index is created and cannot be changed afterwards.
 func (idx *Index) Name() string {
 	return idx.name
 }
 
 // Field creates a Field struct with the specified name and defaults.
+// If the field already exists, it is returned unless an option which was explicitly set both for the field
+// and in the given options differs, in which case an error wrapping ErrFieldOptionsConflict is returned.
+// Calling Field without options returns the existing field, e.g., a field loaded with Client.Schema.
+// CacheTypeDefault and CacheSizeDefault match any cache type and size.
+// Use FieldOrCreate to get an existing field regardless of its options.
 func (idx *Index) Field(name string, options ...interface{}) (*Field, error) {
+	fieldOptions := &FieldOptions{}
+	err := fieldOptions.addOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+	fieldOptions = fieldOptions.withDefaults()
 	if field, ok := idx.fields[name]; ok {
+		if fieldOptions.conflicts(field.options) {
+			return nil, errors.Wrapf(ErrFieldOptionsConflict, "field %s in index %s", name, idx.name)
+		}
 		return field, nil
 	}
-	if err := validateFieldName(name); err != nil {
-		return nil, err
+	return idx.createField(name, fieldOptions)
+}
+
+// FieldOrCreate returns the field with the given name if it exists, regardless of the given options.
+// Otherwise it creates a field with the given name and options.
+func (idx *Index) FieldOrCreate(name string, options ...interface{}) (*Field, error) {
+	if field, ok := idx.fields[name]; ok {
+		return field, nil
 	}
 	fieldOptions := &FieldOptions{}
 	err := fieldOptions.addOptions(options...)
 	if err != nil {
 		return nil, err
 	}
-	fieldOptions = fieldOptions.withDefaults()
+	return idx.createField(name, fieldOptions.withDefaults())
+}
+
+func (idx *Index) createField(name string, fieldOptions *FieldOptions) (*Field, error) {
+	if err := ValidateFieldName(name); err != nil {
+		return nil, err
+	}
 	field := newField(name, idx)
 	field.options = fieldOptions
 	idx.fields[name] = field
 	return field, nil
 }
 
+// FieldByName returns the field with the given name and true if it exists in the index,
+// otherwise nil and false.
+// Unlike Fields, the field is not copied, so changes to it affect the index.
+func (idx *Index) FieldByName(name string) (*Field, bool) {
+	field, ok := idx.fields[name]
+	return field, ok
+}
+
+// existingField returns the field with the given name.
+// Unlike Field, it does not create the field if it does not exist but returns ErrFieldNotFound.
+func (idx *Index) existingField(name string) (*Field, error) {
+	field, ok := idx.fields[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrFieldNotFound, "field %s in index %s", name, idx.name)
+	}
+	return field, nil
+}
+
+// SetBit creates a SetBit query for the field with the given name.
+// If the index has no such field, the query has ErrFieldNotFound as its cause.
+func (idx *Index) SetBit(fieldName string, rowID uint64, columnID uint64) *PQLBaseQuery {
+	field, err := idx.existingField(fieldName)
+	if err != nil {
+		return NewPQLBaseQuery("", idx, err)
+	}
+	return field.SetBit(rowID, columnID)
+}
+
+// Row creates a Row query for the field with the given name.
+// If the index has no such field, it returns an error with ErrFieldNotFound as its cause.
+func (idx *Index) Row(fieldName string, rowID uint64) (*PQLRowQuery, error) {
+	field, err := idx.existingField(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	return field.Row(rowID), nil
+}
+
 // BatchQuery creates a batch query with the given queries.
 func (idx *Index) BatchQuery(queries ...PQLQuery) *PQLBatchQuery {
 	stringQueries := make([]string, 0, len(queries))
@@ -291,15 +1018,56 @@ func (idx *Index) BatchQuery(queries ...PQLQuery) *PQLBatchQuery {
 	}
 }
 
+// BatchQuerySized splits the given queries into batch queries whose PQL is at most maxBytes long.
+// Queries keep their order and each batch has as many queries as fit, which gives the
+// minimum number of batches. It returns an error if a query has an error
+// or if the PQL of a single query is longer than maxBytes.
+func (idx *Index) BatchQuerySized(maxBytes int, queries ...PQLQuery) ([]*PQLBatchQuery, error) {
+	if maxBytes < 1 {
+		return nil, NewError("BatchQuerySized requires maxBytes to be greater than 0")
+	}
+	batches := []*PQLBatchQuery{}
+	var batch *PQLBatchQuery
+	size := 0
+	for i, query := range queries {
+		if err := query.Error(); err != nil {
+			return nil, err
+		}
+		pql := query.serialize()
+		if len(pql) > maxBytes {
+			return nil, NewError(fmt.Sprintf("query %d is %d bytes, which is more than %d bytes", i, len(pql), maxBytes))
+		}
+		if batch == nil || size+len(pql) > maxBytes {
+			batch = idx.BatchQuery()
+			batches = append(batches, batch)
+			size = 0
+		}
+		batch.queries = append(batch.queries, pql)
+		size += len(pql)
+	}
+	return batches, nil
+}
+
 // RawQuery creates a query with the given string.
 // Note that the query is not validated before sending to the server.
 func (idx *Index) RawQuery(query string) *PQLBaseQuery {
 	return NewPQLBaseQuery(query, idx, nil)
 }
 
+// RawRowQuery creates a row query with the given string, so raw PQL can be used with
+// row operations such as Union or Intersect.
+// Note that the query is not validated before sending to the server,
+// and it is not checked that the query returns a row.
+func (idx *Index) RawRowQuery(query string) *PQLRowQuery {
+	return NewPQLRowQuery(query, idx, nil)
+}
+
 // Union creates a Union query.
 // Union performs a logical OR on the results of each ROW_CALL query passed to it.
 func (idx *Index) Union(rows ...*PQLRowQuery) *PQLRowQuery {
+	if len(rows) < 1 {
+		return NewPQLRowQuery("", idx, NewError("Union operation requires at least 1 row"))
+	}
 	return idx.rowOperation("Union", rows...)
 }
 
@@ -312,8 +1080,43 @@ func (idx *Index) Intersect(rows ...*PQLRowQuery) *PQLRowQuery {
 	return idx.rowOperation("Intersect", rows...)
 }
 
-// Difference creates an Intersect query.
+// UnionQ creates a Union query like Union, but accepts PQLQuery values.
+// Each query must be a *PQLRowQuery, otherwise the result has an error.
+func (idx *Index) UnionQ(queries ...PQLQuery) *PQLRowQuery {
+	rows, err := rowQueries("UnionQ", queries)
+	if err != nil {
+		return NewPQLRowQuery("", idx, err)
+	}
+	return idx.Union(rows...)
+}
+
+// IntersectQ creates an Intersect query like Intersect, but accepts PQLQuery values.
+// Each query must be a *PQLRowQuery, otherwise the result has an error.
+func (idx *Index) IntersectQ(queries ...PQLQuery) *PQLRowQuery {
+	rows, err := rowQueries("IntersectQ", queries)
+	if err != nil {
+		return NewPQLRowQuery("", idx, err)
+	}
+	return idx.Intersect(rows...)
+}
+
+func rowQueries(name string, queries []PQLQuery) ([]*PQLRowQuery, error) {
+	rows := make([]*PQLRowQuery, 0, len(queries))
+	for i, query := range queries {
+		row, ok := query.(*PQLRowQuery)
+		if !ok || row == nil {
+			return nil, NewError(fmt.Sprintf("%s argument %d is not a row query", name, i))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Difference creates a Difference query.
 // Difference returns all of the columns from the first ROW_CALL argument passed to it, without the columns from each subsequent ROW_CALL.
+// With a single row, the result contains the same columns as that row.
+// Calling Difference with a single row is deprecated; use DifferenceFrom,
+// which makes the base row explicit and requires at least one subtractor.
 func (idx *Index) Difference(rows ...*PQLRowQuery) *PQLRowQuery {
 	if len(rows) < 1 {
 		return NewPQLRowQuery("", idx, NewError("Difference operation requires at least 1 row"))
@@ -321,6 +1124,21 @@ func (idx *Index) Difference(rows ...*PQLRowQuery) *PQLRowQuery {
 	return idx.rowOperation("Difference", rows...)
 }
 
+// DifferenceFrom creates a Difference query which returns the columns of base
+// without the columns of each of the subtractors.
+// At least one subtractor is required.
+func (idx *Index) DifferenceFrom(base *PQLRowQuery, subtractors ...*PQLRowQuery) *PQLRowQuery {
+	if base == nil {
+		return NewPQLRowQuery("", idx, NewError("DifferenceFrom operation requires a base row"))
+	}
+	if len(subtractors) < 1 {
+		return NewPQLRowQuery("", idx, NewError("DifferenceFrom operation requires at least 1 subtractor"))
+	}
+	rows := make([]*PQLRowQuery, 0, len(subtractors)+1)
+	rows = append(rows, base)
+	return idx.rowOperation("Difference", append(rows, subtractors...)...)
+}
+
 // Xor creates an Xor query.
 func (idx *Index) Xor(rows ...*PQLRowQuery) *PQLRowQuery {
 	if len(rows) < 2 {
@@ -329,12 +1147,38 @@ func (idx *Index) Xor(rows ...*PQLRowQuery) *PQLRowQuery {
 	return idx.rowOperation("Xor", rows...)
 }
 
+// Not creates a Not query.
+// Not returns all of the columns in the index which are not in the given row.
+// It requires the index to track the existence of columns.
+func (idx *Index) Not(row *PQLRowQuery) *PQLRowQuery {
+	if row == nil {
+		return NewPQLRowQuery("", idx, NewError("Not operation requires a row"))
+	}
+	return idx.rowOperation("Not", row)
+}
+
+// All creates an All query.
+// All returns all of the columns in the index.
+// It requires the index to track the existence of columns.
+func (idx *Index) All() *PQLRowQuery {
+	return NewPQLRowQuery("All()", idx, nil)
+}
+
 // Count creates a Count query.
 // Returns the number of set columns in the ROW_CALL passed in.
 func (idx *Index) Count(row *PQLRowQuery) *PQLBaseQuery {
 	return NewPQLBaseQuery(fmt.Sprintf("Count(%s)", row.serialize()), idx, nil)
 }
 
+// CountDistinct creates a query which returns the number of distinct values of the field.
+// This requires a Pilosa server which supports the Distinct call.
+func (idx *Index) CountDistinct(field *Field) *PQLBaseQuery {
+	if field == nil {
+		return NewPQLBaseQuery("", idx, NewError("CountDistinct requires a field"))
+	}
+	return NewPQLBaseQuery(fmt.Sprintf("Count(%s)", field.Distinct().serialize()), idx, nil)
+}
+
 // SetColumnAttrs creates a SetColumnAttrs query.
 // SetColumnAttrs associates arbitrary key/value pairs with a column in an index.
 // Following types are accepted: integer, float, string and boolean types.
@@ -347,6 +1191,272 @@ func (idx *Index) SetColumnAttrs(columnID uint64, attrs map[string]interface{})
 		columnID, attrsString), idx, nil)
 }
 
+// SetColumnAttrsK creates a SetColumnAttrs query using a string column key.
+// This will only work against a Pilosa Enterprise server.
+func (idx *Index) SetColumnAttrsK(columnKey string, attrs map[string]interface{}) *PQLBaseQuery {
+	attrsString, err := createAttributesString(attrs)
+	if err != nil {
+		return NewPQLBaseQuery("", idx, err)
+	}
+	return NewPQLBaseQuery(fmt.Sprintf("SetColumnAttrs(col='%s', %s)",
+		EscapePQLKey(columnKey), attrsString), idx, nil)
+}
+
+// SetColumnAttrsMany creates a batch query with a SetColumnAttrs query for each column in attrs.
+// Queries are ordered by column ID.
+func (idx *Index) SetColumnAttrsMany(attrs map[uint64]map[string]interface{}) *PQLBatchQuery {
+	columnIDs := make([]uint64, 0, len(attrs))
+	for columnID := range attrs {
+		columnIDs = append(columnIDs, columnID)
+	}
+	sort.Slice(columnIDs, func(i, j int) bool { return columnIDs[i] < columnIDs[j] })
+	batch := &PQLBatchQuery{
+		index:   idx,
+		queries: make([]string, 0, len(attrs)),
+	}
+	for _, columnID := range columnIDs {
+		batch.Add(idx.SetColumnAttrs(columnID, attrs[columnID]))
+	}
+	return batch
+}
+
+// SetColumnAttrsManyK creates a batch query with a SetColumnAttrs query for each column key in attrs.
+// Queries are ordered by column key.
+// This will only work against a Pilosa Enterprise server.
+func (idx *Index) SetColumnAttrsManyK(attrs map[string]map[string]interface{}) *PQLBatchQuery {
+	columnKeys := make([]string, 0, len(attrs))
+	for columnKey := range attrs {
+		columnKeys = append(columnKeys, columnKey)
+	}
+	sort.Strings(columnKeys)
+	batch := &PQLBatchQuery{
+		index:   idx,
+		queries: make([]string, 0, len(attrs)),
+	}
+	for _, columnKey := range columnKeys {
+		batch.Add(idx.SetColumnAttrsK(columnKey, attrs[columnKey]))
+	}
+	return batch
+}
+
+// ColumnAttrPair contains the attributes for a column.
+type ColumnAttrPair struct {
+	ColumnID uint64
+	Attrs    map[string]interface{}
+}
+
+// NewColumnAttrPair creates a ColumnAttrPair with the given column ID and attributes.
+// Returns an error if an attribute name is not valid.
+func NewColumnAttrPair(columnID uint64, attrs map[string]interface{}) (ColumnAttrPair, error) {
+	for k := range attrs {
+		if err := validateLabel(k); err != nil {
+			return ColumnAttrPair{}, err
+		}
+	}
+	return ColumnAttrPair{
+		ColumnID: columnID,
+		Attrs:    attrs,
+	}, nil
+}
+
+// SetColumnAttrsBatch creates a batch query with a SetColumnAttrs query for each pair.
+// Pairs are added to the batch in order, so if a column ID occurs more than once
+// the attributes of the last pair win.
+// Pairs with invalid attributes are left out of the batch and their errors are returned
+// keyed by column ID, so the batch can be sent for the valid pairs.
+// If a column ID occurs in more than one invalid pair, the error of the last one is returned.
+// The returned map is empty if all pairs are valid.
+func (idx *Index) SetColumnAttrsBatch(pairs []ColumnAttrPair) (*PQLBatchQuery, map[uint64]error) {
+	batch := &PQLBatchQuery{
+		index:   idx,
+		queries: make([]string, 0, len(pairs)),
+	}
+	errs := map[uint64]error{}
+	for _, pair := range pairs {
+		query := idx.SetColumnAttrs(pair.ColumnID, pair.Attrs)
+		if err := query.Error(); err != nil {
+			errs[pair.ColumnID] = err
+			continue
+		}
+		batch.Add(query)
+	}
+	return batch, errs
+}
+
+// TopNCross creates a batch query with a TopN query with the given item count for each field.
+// All fields must belong to this index.
+//
+// The result of TopN is a list of row IDs and counts, not a row, so PQL cannot
+// combine the results of several TopN calls; there is no TopNUnion query.
+// To get the union of the top rows of the fields, merge the results of this
+// query on the client:
+//
+//	response, err := client.Query(index.TopNCross(n, fields))
+//	// handle err
+//	rowIDs := map[uint64]bool{}
+//	for _, result := range response.Results() {
+//		for _, item := range result.CountItems() {
+//			rowIDs[item.ID] = true
+//		}
+//	}
+//
+// Note that row IDs of different fields are unrelated, so keep the field with the ID if it matters.
+func (idx *Index) TopNCross(n uint64, fields []*Field) *PQLBatchQuery {
+	return idx.fieldsBatch("TopNCross", fields, func(field *Field) PQLQuery {
+		return field.TopNFiltered(n, nil)
+	})
+}
+
+// CountAll creates a batch query which counts the columns of the row for each row of each field.
+// A TopN query with no item limit is created for each field, so the result of each
+// query contains the count of every row of that field among the columns in the row,
+// which is useful for faceted search.
+// Pass nil for the row to count all columns.
+// All fields must belong to this index.
+func (idx *Index) CountAll(fields []*Field, row *PQLRowQuery) *PQLBatchQuery {
+	return idx.fieldsBatch("CountAll", fields, func(field *Field) PQLQuery {
+		return field.TopNFiltered(0, row)
+	})
+}
+
+func (idx *Index) fieldsBatch(name string, fields []*Field, query func(field *Field) PQLQuery) *PQLBatchQuery {
+	batch := &PQLBatchQuery{
+		index:   idx,
+		queries: make([]string, 0, len(fields)),
+	}
+	for i, field := range fields {
+		if field == nil {
+			batch.err = NewError(fmt.Sprintf("%s requires fields of index %s, field %d is nil", name, idx.name, i))
+			return batch
+		}
+		if !sameIndex(field.index, idx) {
+			batch.err = NewError(fmt.Sprintf("%s requires fields of index %s, field %s belongs to another index", name, idx.name, field.name))
+			return batch
+		}
+		batch.Add(query(field))
+	}
+	return batch
+}
+
+// BatchQueryForRowIDs creates a batch query with the query returned by makeQuery for each of the given row IDs.
+// E.g., passing a makeQuery which returns field.Row(rowID).Count() counts the columns of each row.
+// The field must belong to this index.
+func (idx *Index) BatchQueryForRowIDs(field *Field, rowIDs []uint64, makeQuery func(rowID uint64) PQLQuery) *PQLBatchQuery {
+	batch := idx.fieldBatch("BatchQueryForRowIDs", field, len(rowIDs))
+	if batch.err != nil {
+		return batch
+	}
+	for _, rowID := range rowIDs {
+		batch.Add(makeQuery(rowID))
+	}
+	return batch
+}
+
+// BatchQueryForRowKeys creates a batch query with the query returned by makeQuery for each of the given row keys.
+// It returns a batch query with an error wrapping ErrInvalidKey if a row key is empty.
+// makeQuery receives the unescaped key; RowK and the other K methods escape it,
+// PQL built by hand should use EscapePQLKey.
+// The field must belong to this index.
+// This will only work against a Pilosa Enterprise server.
+func (idx *Index) BatchQueryForRowKeys(field *Field, rowKeys []string, makeQuery func(rowKey string) PQLQuery) *PQLBatchQuery {
+	batch := idx.fieldBatch("BatchQueryForRowKeys", field, len(rowKeys))
+	if batch.err != nil {
+		return batch
+	}
+	for i, rowKey := range rowKeys {
+		if rowKey == "" {
+			batch.err = errors.Wrapf(ErrInvalidKey, "row key %d is empty", i)
+			return batch
+		}
+	}
+	for _, rowKey := range rowKeys {
+		batch.Add(makeQuery(rowKey))
+	}
+	return batch
+}
+
+func (idx *Index) fieldBatch(name string, field *Field, size int) *PQLBatchQuery {
+	batch := &PQLBatchQuery{
+		index:   idx,
+		queries: make([]string, 0, size),
+	}
+	if field == nil || !sameIndex(field.index, idx) {
+		batch.err = NewError(fmt.Sprintf("%s requires a field of index %s", name, idx.name))
+	}
+	return batch
+}
+
+// SetBitMatrix creates a batch query with a SetBit query for each nonzero entry of the matrix.
+// A nonzero matrix[i][j] sets the bit at row i and column j of the given field.
+// The field must belong to this index.
+func (idx *Index) SetBitMatrix(field *Field, matrix [][]uint64) *PQLBatchQuery {
+	return idx.bitMatrix(field, matrix, "SetBit")
+}
+
+// ClearBitMatrix creates a batch query with a ClearBit query for each nonzero entry of the matrix.
+// A nonzero matrix[i][j] clears the bit at row i and column j of the given field.
+// The field must belong to this index.
+func (idx *Index) ClearBitMatrix(field *Field, matrix [][]uint64) *PQLBatchQuery {
+	return idx.bitMatrix(field, matrix, "ClearBit")
+}
+
+// SetBitsCSV creates a batch query with a SetBit query for each column ID in the comma separated csv.
+// E.g., "1,2,3" sets the bits at columns 1, 2 and 3 of the given row.
+// Spaces around the column IDs are ignored. The field must belong to this index.
+func (idx *Index) SetBitsCSV(field *Field, rowID uint64, csv string) (*PQLBatchQuery, error) {
+	if field == nil || !sameIndex(field.index, idx) {
+		return nil, NewError(fmt.Sprintf("SetBitsCSV requires a field of index %s", idx.name))
+	}
+	if strings.TrimSpace(csv) == "" {
+		return idx.BatchQuery(), nil
+	}
+	parts := strings.Split(csv, ",")
+	queries := make([]string, 0, len(parts))
+	for _, part := range parts {
+		columnID, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing column ID '%s'", part)
+		}
+		queries = append(queries, field.setBitPQL(rowID, columnID))
+	}
+	field.logQuery("SetBitsCSV", rowID, nil)
+	return &PQLBatchQuery{
+		index:   idx,
+		queries: queries,
+	}, nil
+}
+
+func (idx *Index) bitMatrix(field *Field, matrix [][]uint64, name string) *PQLBatchQuery {
+	if field == nil || !sameIndex(field.index, idx) {
+		return &PQLBatchQuery{
+			index: idx,
+			err:   NewError(fmt.Sprintf("%s matrix requires a field of index %s", name, idx.name)),
+		}
+	}
+	count := 0
+	for _, row := range matrix {
+		for _, value := range row {
+			if value != 0 {
+				count++
+			}
+		}
+	}
+	queries := make([]string, 0, count)
+	for rowID, row := range matrix {
+		for columnID, value := range row {
+			if value != 0 {
+				queries = append(queries, fmt.Sprintf("%s(row=%d, field='%s', col=%d)",
+					name, rowID, escapeString(field.name), columnID))
+			}
+		}
+	}
+	field.logQuery(name+"Matrix", nil, nil)
+	return &PQLBatchQuery{
+		index:   idx,
+		queries: queries,
+	}
+}
+
 func (idx *Index) rowOperation(name string, rows ...*PQLRowQuery) *PQLRowQuery {
 	var err error
 	args := make([]string, 0, len(rows))
@@ -366,13 +1476,32 @@ type FieldInfo struct {
 
 // FieldOptions contains options to customize Field objects and field queries.
 type FieldOptions struct {
-	fieldType   FieldType
-	timeQuantum TimeQuantum
-	cacheType   CacheType
-	cacheSize   int
-	min         int64
-	max         int64
-}
+	fieldType      FieldType
+	timeQuantum    TimeQuantum
+	cacheType      CacheType
+	cacheSize      int
+	min            int64
+	max            int64
+	noStandardView bool
+	// explicit has a bit set for each option which was set by the user
+	explicit fieldOptionFlags
+	// timestampTruncate is the time quantum SetBitTimestamp truncates timestamps to, if set
+	timestampTruncate TimeQuantum
+}
+
+// fieldOptionFlags is a bitmask of field options.
+type fieldOptionFlags uint8
+
+const (
+	fieldOptionType fieldOptionFlags = 1 << iota
+	fieldOptionTimeQuantum
+	fieldOptionCacheType
+	fieldOptionCacheSize
+	fieldOptionMin
+	fieldOptionMax
+	fieldOptionNoStandardView
+	fieldOptionTimestampTruncate
+)
 
 func (fo *FieldOptions) withDefaults() (updated *FieldOptions) {
 	// copy options so the original is not updated
@@ -381,22 +1510,89 @@ func (fo *FieldOptions) withDefaults() (updated *FieldOptions) {
 	return
 }
 
+// ClearDefaults returns a copy of the options where the options which were not
+// explicitly set by the user are reset to their default values, e.g., the cache type
+// is CacheTypeDefault unless it was set with OptFieldSet.
+// Options decoded from JSON or loaded from the server are considered explicitly set
+// if they do not have their default values.
+func (fo *FieldOptions) ClearDefaults() *FieldOptions {
+	cleared := &FieldOptions{explicit: fo.explicit}
+	if fo.isExplicit(fieldOptionType) {
+		cleared.fieldType = fo.fieldType
+	}
+	if fo.isExplicit(fieldOptionTimeQuantum) {
+		cleared.timeQuantum = fo.timeQuantum
+	}
+	if fo.isExplicit(fieldOptionCacheType) {
+		cleared.cacheType = fo.cacheType
+	}
+	if fo.isExplicit(fieldOptionCacheSize) {
+		cleared.cacheSize = fo.cacheSize
+	}
+	if fo.isExplicit(fieldOptionMin) {
+		cleared.min = fo.min
+	}
+	if fo.isExplicit(fieldOptionMax) {
+		cleared.max = fo.max
+	}
+	if fo.isExplicit(fieldOptionNoStandardView) {
+		cleared.noStandardView = fo.noStandardView
+	}
+	if fo.isExplicit(fieldOptionTimestampTruncate) {
+		cleared.timestampTruncate = fo.timestampTruncate
+	}
+	return cleared
+}
+
+// fieldOptionNames are the names of the field options, as in the JSON returned by FieldOptions.String.
+var fieldOptionNames = []struct {
+	flag fieldOptionFlags
+	name string
+}{
+	{fieldOptionType, "type"},
+	{fieldOptionTimeQuantum, "timeQuantum"},
+	{fieldOptionCacheType, "cacheType"},
+	{fieldOptionCacheSize, "cacheSize"},
+	{fieldOptionMin, "min"},
+	{fieldOptionMax, "max"},
+	{fieldOptionNoStandardView, "noStandardView"},
+}
+
+// ExplicitlySet returns the names of the options which were explicitly set by the user.
+// The names are the same as the ones in the JSON returned by String.
+func (fo *FieldOptions) ExplicitlySet() []string {
+	names := []string{}
+	for _, option := range fieldOptionNames {
+		if fo.isExplicit(option.flag) {
+			names = append(names, option.name)
+		}
+	}
+	return names
+}
+
+func (fo *FieldOptions) isExplicit(flags fieldOptionFlags) bool {
+	return fo.explicit&flags == flags
+}
+
 func (fo FieldOptions) String() string {
 	mopt := map[string]interface{}{}
 
-	switch fo.fieldType {
-	case FieldTypeSet:
+	switch {
+	case fo.fieldType.IsSet():
 		if fo.cacheType != CacheTypeDefault {
 			mopt["cacheType"] = string(fo.cacheType)
 		}
 		if fo.cacheSize > 0 {
 			mopt["cacheSize"] = fo.cacheSize
 		}
-	case FieldTypeInt:
+	case fo.fieldType.IsInteger():
 		mopt["min"] = fo.min
 		mopt["max"] = fo.max
-	case FieldTypeTime:
+	case fo.fieldType.IsTime():
 		mopt["timeQuantum"] = string(fo.timeQuantum)
+		if fo.noStandardView {
+			mopt["noStandardView"] = true
+		}
 	}
 
 	if fo.fieldType != FieldTypeDefault {
@@ -405,6 +1601,98 @@ func (fo FieldOptions) String() string {
 	return fmt.Sprintf(`{"options":%s}`, encodeMap(mopt))
 }
 
+// UnmarshalJSON parses field options in the format returned by FieldOptions.String.
+func (fo *FieldOptions) UnmarshalJSON(data []byte) error {
+	var root struct {
+		Options struct {
+			FieldType      FieldType   `json:"type"`
+			CacheType      CacheType   `json:"cacheType"`
+			CacheSize      int         `json:"cacheSize"`
+			TimeQuantum    TimeQuantum `json:"timeQuantum"`
+			Min            int64       `json:"min"`
+			Max            int64       `json:"max"`
+			NoStandardView bool        `json:"noStandardView"`
+		} `json:"options"`
+	}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return errors.Wrap(err, "unmarshaling field options")
+	}
+	opts := root.Options
+	*fo = FieldOptions{
+		fieldType:      opts.FieldType,
+		timeQuantum:    opts.TimeQuantum,
+		cacheType:      opts.CacheType,
+		cacheSize:      opts.CacheSize,
+		min:            opts.Min,
+		max:            opts.Max,
+		noStandardView: opts.NoStandardView,
+	}
+	fo.markPresent()
+	return nil
+}
+
+// markPresent marks the options which do not have their default values as explicitly set.
+// It is used for options which were not created by the user, e.g., decoded from JSON.
+func (fo *FieldOptions) markPresent() {
+	present := []struct {
+		flags fieldOptionFlags
+		ok    bool
+	}{
+		{fieldOptionType, fo.fieldType != FieldTypeDefault},
+		{fieldOptionTimeQuantum, fo.timeQuantum != TimeQuantumNone},
+		{fieldOptionCacheType, fo.cacheType != CacheTypeDefault},
+		{fieldOptionCacheSize, fo.cacheSize != 0},
+		{fieldOptionMin | fieldOptionMax, fo.fieldType.IsInteger()},
+		{fieldOptionNoStandardView, fo.noStandardView},
+	}
+	for _, p := range present {
+		if p.ok {
+			fo.explicit |= p.flags
+		}
+	}
+}
+
+// Equal returns true if the given field options are the same as these ones.
+// Whether the options were set explicitly is not compared.
+// Client side options, such as the one set by OptTimestampTruncate, are not compared.
+func (fo *FieldOptions) Equal(other *FieldOptions) bool {
+	if fo == nil || other == nil {
+		return fo == other
+	}
+	return fo.fieldType == other.fieldType &&
+		fo.timeQuantum == other.timeQuantum &&
+		fo.cacheType == other.cacheType &&
+		fo.cacheSize == other.cacheSize &&
+		fo.min == other.min &&
+		fo.max == other.max &&
+		fo.noStandardView == other.noStandardView
+}
+
+// conflicts returns true if an option which was explicitly set both in these options and in the existing
+// options has different values.
+// The default cache type and size do not conflict with any cache type and size.
+func (fo *FieldOptions) conflicts(existing *FieldOptions) bool {
+	differs := []struct {
+		flags fieldOptionFlags
+		ok    bool
+	}{
+		{fieldOptionType, fo.fieldType != existing.fieldType},
+		{fieldOptionTimeQuantum, fo.timeQuantum != existing.timeQuantum},
+		{fieldOptionCacheType, fo.cacheType != CacheTypeDefault && fo.cacheType != existing.cacheType},
+		{fieldOptionCacheSize, fo.cacheSize != CacheSizeDefault && fo.cacheSize != existing.cacheSize},
+		{fieldOptionMin, fo.min != existing.min},
+		{fieldOptionMax, fo.max != existing.max},
+		{fieldOptionNoStandardView, fo.noStandardView != existing.noStandardView},
+		{fieldOptionTimestampTruncate, fo.timestampTruncate != existing.timestampTruncate},
+	}
+	for _, d := range differs {
+		if d.ok && fo.isExplicit(d.flags) && existing.isExplicit(d.flags) {
+			return true
+		}
+	}
+	return false
+}
+
 func (fo *FieldOptions) addOptions(options ...interface{}) error {
 	for i, option := range options {
 		switch o := option.(type) {
@@ -423,8 +1711,14 @@ func (fo *FieldOptions) addOptions(options ...interface{}) error {
 			if err != nil {
 				return err
 			}
+		case FieldOptionApplier:
+			err := o.Apply(fo)
+			if err != nil {
+				return err
+			}
 		case TimeQuantum:
 			fo.timeQuantum = o
+			fo.explicit |= fieldOptionTimeQuantum
 		default:
 			return ErrInvalidFieldOption
 		}
@@ -435,6 +1729,18 @@ func (fo *FieldOptions) addOptions(options ...interface{}) error {
 // FieldOption is used to pass an option to index.Field function.
 type FieldOption func(options *FieldOptions) error
 
+// FieldOptionApplier is an option for index.Field function which has a name.
+// Unlike a FieldOption, it can be inspected, so other packages can define
+// options which carry their own metadata.
+// FieldOptionSet, FieldOptionInt, FieldOptionTime, FieldOptionTimeNoStandardView and
+// FieldOptionTimestampTruncate are the appliers of the options in this package.
+type FieldOptionApplier interface {
+	// Apply sets the option on the given field options.
+	Apply(options *FieldOptions) error
+	// Name returns the name of the option.
+	Name() string
+}
+
 // OptFieldSet adds a set field.
 // Specify CacheTypeDefault for the default cache type.
 // Specify CacheSizeDefault for the default cache size.
@@ -446,6 +1752,7 @@ func OptFieldSet(cacheType CacheType, cacheSize int) FieldOption {
 		options.fieldType = FieldTypeSet
 		options.cacheType = cacheType
 		options.cacheSize = cacheSize
+		options.explicit |= fieldOptionType | fieldOptionCacheType | fieldOptionCacheSize
 		return nil
 	}
 }
@@ -459,6 +1766,7 @@ func OptFieldInt(min int64, max int64) FieldOption {
 		}
 		options.min = min
 		options.max = max
+		options.explicit |= fieldOptionType | fieldOptionMin | fieldOptionMax
 		return nil
 	}
 }
@@ -467,10 +1775,117 @@ func OptFieldTime(quantum TimeQuantum) FieldOption {
 	return func(options *FieldOptions) error {
 		options.fieldType = FieldTypeTime
 		options.timeQuantum = quantum
+		options.explicit |= fieldOptionType | fieldOptionTimeQuantum
+		return nil
+	}
+}
+
+// OptFieldTimeNoStandardView adds a time field which does not have the standard view.
+// Bits set on such a field are only stored in the time quantum views,
+// so Row queries do not return them; use Range queries instead.
+func OptFieldTimeNoStandardView(quantum TimeQuantum) FieldOption {
+	return func(options *FieldOptions) error {
+		options.fieldType = FieldTypeTime
+		options.timeQuantum = quantum
+		options.noStandardView = true
+		options.explicit |= fieldOptionType | fieldOptionTimeQuantum | fieldOptionNoStandardView
+		return nil
+	}
+}
+
+// OptTimestampTruncate is a client side field option which makes SetBitTimestamp and
+// SetBitTimestampK truncate timestamps to the finest unit of the given time quantum,
+// in UTC, as TruncateToQuantum does.
+// Days and hours are truncated to 24 hours and 1 hour; years and months have no fixed
+// duration, so they are truncated to the start of the calendar year or month.
+func OptTimestampTruncate(quantum TimeQuantum) FieldOption {
+	return func(options *FieldOptions) error {
+		if _, err := quantum.Duration(); err != nil {
+			return ErrInvalidFieldOption
+		}
+		options.timestampTruncate = quantum
+		options.explicit |= fieldOptionTimestampTruncate
 		return nil
 	}
 }
 
+// FieldOptionSet is the FieldOptionApplier of OptFieldSet.
+type FieldOptionSet struct {
+	CacheType CacheType
+	CacheSize int
+}
+
+// Apply sets the option on the given field options.
+func (o FieldOptionSet) Apply(options *FieldOptions) error {
+	return OptFieldSet(o.CacheType, o.CacheSize)(options)
+}
+
+// Name returns the name of the option.
+func (o FieldOptionSet) Name() string {
+	return "OptFieldSet"
+}
+
+// FieldOptionInt is the FieldOptionApplier of OptFieldInt.
+type FieldOptionInt struct {
+	Min int64
+	Max int64
+}
+
+// Apply sets the option on the given field options.
+func (o FieldOptionInt) Apply(options *FieldOptions) error {
+	return OptFieldInt(o.Min, o.Max)(options)
+}
+
+// Name returns the name of the option.
+func (o FieldOptionInt) Name() string {
+	return "OptFieldInt"
+}
+
+// FieldOptionTime is the FieldOptionApplier of OptFieldTime.
+type FieldOptionTime struct {
+	Quantum TimeQuantum
+}
+
+// Apply sets the option on the given field options.
+func (o FieldOptionTime) Apply(options *FieldOptions) error {
+	return OptFieldTime(o.Quantum)(options)
+}
+
+// Name returns the name of the option.
+func (o FieldOptionTime) Name() string {
+	return "OptFieldTime"
+}
+
+// FieldOptionTimeNoStandardView is the FieldOptionApplier of OptFieldTimeNoStandardView.
+type FieldOptionTimeNoStandardView struct {
+	Quantum TimeQuantum
+}
+
+// Apply sets the option on the given field options.
+func (o FieldOptionTimeNoStandardView) Apply(options *FieldOptions) error {
+	return OptFieldTimeNoStandardView(o.Quantum)(options)
+}
+
+// Name returns the name of the option.
+func (o FieldOptionTimeNoStandardView) Name() string {
+	return "OptFieldTimeNoStandardView"
+}
+
+// FieldOptionTimestampTruncate is the FieldOptionApplier of OptTimestampTruncate.
+type FieldOptionTimestampTruncate struct {
+	Quantum TimeQuantum
+}
+
+// Apply sets the option on the given field options.
+func (o FieldOptionTimestampTruncate) Apply(options *FieldOptions) error {
+	return OptTimestampTruncate(o.Quantum)(options)
+}
+
+// Name returns the name of the option.
+func (o FieldOptionTimestampTruncate) Name() string {
+	return "OptTimestampTruncate"
+}
+
 // Field structs are used to segment and define different functional characteristics within your entire index.
 // You can think of a Field as a table-like data partition within your Index.
 // Row-level attributes are namespaced at the Field level.
@@ -478,6 +1893,13 @@ type Field struct {
 	name    string
 	index   *Index
 	options *FieldOptions
+	logger  QueryLogger
+}
+
+// QueryLogger logs the queries built by a field.
+// A *slog.Logger can be used as a QueryLogger.
+type QueryLogger interface {
+	Debug(msg string, args ...interface{})
 }
 
 func (f *Field) String() string {
@@ -493,6 +1915,7 @@ func newField(name string, index *Index) *Field {
 }
 
 // Name returns the name of the field
+// The name is validated when the field is created and cannot be changed afterwards.
 func (f *Field) Name() string {
 	return f.name
 }
@@ -500,79 +1923,354 @@ func (f *Field) Name() string {
 func (f *Field) copy() *Field {
 	field := newField(f.name, f.index)
 	*field.options = *f.options
+	field.logger = f.logger
+	return field
+}
+
+// WithLogger returns a copy of the field which logs the queries it builds
+// to the given logger, at debug level.
+// Each entry has the field_name, query_type, row_id and col_id attributes;
+// query_type is the name of the PQL call, row_id and col_id are keys for the K variants
+// and nil if the query has no row or column.
+// Batch queries, e.g., those built by SetBitRange, are logged once with the name of the method as query_type.
+// The copy belongs to the same index, but it is not returned by Index.Field.
+// Pass nil to disable logging.
+func (f *Field) WithLogger(logger QueryLogger) *Field {
+	field := f.copy()
+	field.logger = logger
 	return field
 }
 
+// WithName returns a copy of the field with the given name and the same options.
+// The copy belongs to the same index, but it is not registered in it;
+// call Index.Field with the same options to add it to the index.
+func (f *Field) WithName(name string) (*Field, error) {
+	if err := ValidateFieldName(name); err != nil {
+		return nil, err
+	}
+	field := f.copy()
+	field.name = name
+	return field, nil
+}
+
+// rowQuery creates a row query of the field and logs it.
+func (f *Field) rowQuery(pql string, queryType string, row interface{}, column interface{}) *PQLRowQuery {
+	f.logQuery(queryType, row, column)
+	return NewPQLRowQuery(pql, f.index, nil)
+}
+
+// baseQuery creates a query of the field and logs it.
+func (f *Field) baseQuery(pql string, queryType string, row interface{}, column interface{}) *PQLBaseQuery {
+	f.logQuery(queryType, row, column)
+	return NewPQLBaseQuery(pql, f.index, nil)
+}
+
+// logQuery logs a query built by the field; row and column are nil if the query has none.
+func (f *Field) logQuery(queryType string, row interface{}, column interface{}) {
+	if f.logger == nil {
+		return
+	}
+	f.logger.Debug("pilosa query built",
+		"field_name", f.name, "query_type", queryType, "row_id", row, "col_id", column)
+}
+
+func (f *Field) setBitPQL(rowID uint64, columnID uint64) string {
+	return fmt.Sprintf("SetBit(row=%d, field='%s', col=%d)", rowID, escapeString(f.name), columnID)
+}
+
 // Row creates a Row query.
 // Row retrieves the indices of all the set columns in a row.
 // It also retrieves any attributes set on that row or column.
 func (f *Field) Row(rowID uint64) *PQLRowQuery {
-	return NewPQLRowQuery(fmt.Sprintf("Bitmap(row=%d, field='%s')",
-		rowID, f.name), f.index, nil)
+	return f.rowQuery(fmt.Sprintf("Bitmap(row=%d, field='%s')",
+		rowID, escapeString(f.name)), "Bitmap", rowID, nil)
+}
+
+// RowInt creates a Row query using a signed row ID, which may be negative.
+// It is only valid for int fields, for other fields the query has an error.
+func (f *Field) RowInt(rowID int64) *PQLRowQuery {
+	if !f.options.fieldType.IsInteger() {
+		return NewPQLRowQuery("", f.index, NewError("RowInt requires an int field"))
+	}
+	return f.rowQuery(fmt.Sprintf("Bitmap(row=%d, field='%s')",
+		rowID, escapeString(f.name)), "Bitmap", rowID, nil)
 }
 
 // RowK creates a Row query using a string key instead of an integer
 // rowID. This will only work against a Pilosa Enterprise server.
 func (f *Field) RowK(rowKey string) *PQLRowQuery {
-	return NewPQLRowQuery(fmt.Sprintf("Bitmap(row='%s', field='%s')",
-		rowKey, f.name), f.index, nil)
+	return f.rowQuery(fmt.Sprintf("Bitmap(row='%s', field='%s')",
+		EscapePQLKey(rowKey), escapeString(f.name)), "Bitmap", rowKey, nil)
 }
 
 // SetBit creates a SetBit query.
 // SetBit, assigns a value of 1 to a bit in the binary matrix, thus associating the given row in the given field with the given column.
 func (f *Field) SetBit(rowID uint64, columnID uint64) *PQLBaseQuery {
-	return NewPQLBaseQuery(fmt.Sprintf("SetBit(row=%d, field='%s', col=%d)",
-		rowID, f.name, columnID), f.index, nil)
+	return f.baseQuery(f.setBitPQL(rowID, columnID), "SetBit", rowID, columnID)
 }
 
 // SetBitK creates a SetBit query using string row and column keys. This will
 // only work against a Pilosa Enterprise server.
 func (f *Field) SetBitK(rowKey string, columnKey string) *PQLBaseQuery {
-	return NewPQLBaseQuery(fmt.Sprintf("SetBit(row='%s', field='%s', col='%s')",
-		rowKey, f.name, columnKey), f.index, nil)
+	return f.baseQuery(fmt.Sprintf("SetBit(row='%s', field='%s', col='%s')",
+		EscapePQLKey(rowKey), escapeString(f.name), EscapePQLKey(columnKey)), "SetBit", rowKey, columnKey)
+}
+
+// MaxBatchSize is the maximum number of queries SetBitRange creates in a single batch query.
+var MaxBatchSize uint64 = 100000
+
+// SetBitRange creates a batch query which sets the bits of the row for columns from startCol to endCol, inclusive.
+// Pilosa does not have a PQL call for setting a range of bits, so a SetBit query is created for each column.
+// Ranges with more than MaxBatchSize columns are rejected; consider using Client.ImportField for those.
+func (f *Field) SetBitRange(rowID uint64, startCol uint64, endCol uint64) *PQLBatchQuery {
+	if startCol > endCol {
+		return &PQLBatchQuery{
+			index: f.index,
+			err:   NewError("SetBitRange requires startCol to be less than or equal to endCol"),
+		}
+	}
+	if endCol-startCol >= MaxBatchSize {
+		return &PQLBatchQuery{
+			index: f.index,
+			err:   NewError(fmt.Sprintf("SetBitRange cannot set more than %d bits", MaxBatchSize)),
+		}
+	}
+	queries := make([]string, 0, endCol-startCol+1)
+	for col := startCol; ; col++ {
+		queries = append(queries, f.setBitPQL(rowID, col))
+		if col == endCol {
+			break
+		}
+	}
+	f.logQuery("SetBitRange", rowID, nil)
+	return &PQLBatchQuery{
+		index:   f.index,
+		queries: queries,
+	}
+}
+
+// RowID is the ID of a row.
+type RowID uint64
+
+// ColumnID is the ID of a column.
+type ColumnID uint64
+
+// Cell is the position of a bit in a field.
+// Using a Cell instead of separate row and column IDs prevents swapping them by mistake.
+type Cell struct {
+	Row RowID
+	Col ColumnID
+}
+
+// SetCell creates a SetBit query for the given cell.
+func (f *Field) SetCell(c Cell) *PQLBaseQuery {
+	return f.SetBit(uint64(c.Row), uint64(c.Col))
+}
+
+// ClearCell creates a ClearBit query for the given cell.
+func (f *Field) ClearCell(c Cell) *PQLBaseQuery {
+	return f.ClearBit(uint64(c.Row), uint64(c.Col))
+}
+
+// SetCells creates a batch query with a SetBit query for each of the given cells.
+func (f *Field) SetCells(cells []Cell) *PQLBatchQuery {
+	queries := make([]string, 0, len(cells))
+	for _, c := range cells {
+		queries = append(queries, f.setBitPQL(uint64(c.Row), uint64(c.Col)))
+	}
+	f.logQuery("SetCells", nil, nil)
+	return &PQLBatchQuery{
+		index:   f.index,
+		queries: queries,
+	}
+}
+
+// SetBitsFromMap creates a batch query with a SetBit query for each column of each row in rowCols.
+// Queries are ordered by row ID, then by column ID. The column ID slices are not modified.
+func (f *Field) SetBitsFromMap(rowCols map[uint64][]uint64) *PQLBatchQuery {
+	rowIDs := make([]uint64, 0, len(rowCols))
+	size := 0
+	for rowID, columnIDs := range rowCols {
+		rowIDs = append(rowIDs, rowID)
+		size += len(columnIDs)
+	}
+	sort.Slice(rowIDs, func(i, j int) bool { return rowIDs[i] < rowIDs[j] })
+	queries := make([]string, 0, size)
+	for _, rowID := range rowIDs {
+		columnIDs := append([]uint64(nil), rowCols[rowID]...)
+		sort.Slice(columnIDs, func(i, j int) bool { return columnIDs[i] < columnIDs[j] })
+		for _, columnID := range columnIDs {
+			queries = append(queries, f.setBitPQL(rowID, columnID))
+		}
+	}
+	f.logQuery("SetBitsFromMap", nil, nil)
+	return &PQLBatchQuery{
+		index:   f.index,
+		queries: queries,
+	}
+}
+
+// SetBitIf creates a SetBit query if condition is true.
+// Otherwise it returns a query with empty PQL, which adds nothing to the PQL of a batch query;
+// use PQLBatchQuery.Compact to remove it from the batch.
+func (f *Field) SetBitIf(condition bool, rowID uint64, columnID uint64) *PQLBaseQuery {
+	if !condition {
+		return NewPQLBaseQuery("", f.index, nil)
+	}
+	return f.SetBit(rowID, columnID)
 }
 
 // SetBitTimestamp creates a SetBit query with timestamp.
 // SetBit, assigns a value of 1 to a bit in the binary matrix,
 // thus associating the given row in the given field with the given column.
 func (f *Field) SetBitTimestamp(rowID uint64, columnID uint64, timestamp time.Time) *PQLBaseQuery {
-	return NewPQLBaseQuery(fmt.Sprintf("SetBit(row=%d, field='%s', col=%d, timestamp='%s')",
-		rowID, f.name, columnID, timestamp.Format(timeFormat)),
-		f.index, nil)
+	return f.baseQuery(fmt.Sprintf("SetBit(row=%d, field='%s', col=%d, timestamp='%s')",
+		rowID, escapeString(f.name), columnID, f.truncateTimestamp(timestamp).Format(timeFormat)),
+		"SetBit", rowID, columnID)
 }
 
 // SetBitTimestampK creates a SetBitK query with timestamp.
 func (f *Field) SetBitTimestampK(rowKey string, columnKey string, timestamp time.Time) *PQLBaseQuery {
-	return NewPQLBaseQuery(fmt.Sprintf("SetBit(row='%s', field='%s', col='%s', timestamp='%s')",
-		rowKey, f.name, columnKey, timestamp.Format(timeFormat)),
-		f.index, nil)
+	return f.baseQuery(fmt.Sprintf("SetBit(row='%s', field='%s', col='%s', timestamp='%s')",
+		EscapePQLKey(rowKey), escapeString(f.name), EscapePQLKey(columnKey), f.truncateTimestamp(timestamp).Format(timeFormat)),
+		"SetBit", rowKey, columnKey)
 }
 
 // ClearBit creates a ClearBit query.
 // ClearBit, assigns a value of 0 to a bit in the binary matrix, thus disassociating the given row in the given field from the given column.
+// There is no call to clear a column in all fields, nor to clear the value of an integer field.
+// Since queries are created without reading data, clearing a column requires a ClearBit query
+// for each row which has the column set, with the rows found by querying the server first.
 func (f *Field) ClearBit(rowID uint64, columnID uint64) *PQLBaseQuery {
-	return NewPQLBaseQuery(fmt.Sprintf("ClearBit(row=%d, field='%s', col=%d)",
-		rowID, f.name, columnID), f.index, nil)
+	return f.baseQuery(fmt.Sprintf("ClearBit(row=%d, field='%s', col=%d)",
+		rowID, escapeString(f.name), columnID), "ClearBit", rowID, columnID)
 }
 
 // ClearBitK creates a ClearBit query using string row and column keys. This
 // will only work against a Pilosa Enterprise server.
 func (f *Field) ClearBitK(rowKey string, columnKey string) *PQLBaseQuery {
-	return NewPQLBaseQuery(fmt.Sprintf("ClearBit(row='%s', field='%s', col='%s')",
-		rowKey, f.name, columnKey), f.index, nil)
+	return f.baseQuery(fmt.Sprintf("ClearBit(row='%s', field='%s', col='%s')",
+		EscapePQLKey(rowKey), escapeString(f.name), EscapePQLKey(columnKey)), "ClearBit", rowKey, columnKey)
+}
+
+// ClearRow creates a ClearRow query.
+// ClearRow clears all of the bits in the given row.
+// This requires a Pilosa server which supports the ClearRow call.
+// There is no call to clear all rows of a field, delete and recreate the field instead.
+func (f *Field) ClearRow(rowID uint64) *PQLBaseQuery {
+	return f.baseQuery(fmt.Sprintf("ClearRow(row=%d, field='%s')",
+		rowID, escapeString(f.name)), "ClearRow", rowID, nil)
+}
+
+// ClearRowK creates a ClearRow query using a string row key. This
+// will only work against a Pilosa Enterprise server.
+func (f *Field) ClearRowK(rowKey string) *PQLBaseQuery {
+	return f.baseQuery(fmt.Sprintf("ClearRow(row='%s', field='%s')",
+		EscapePQLKey(rowKey), escapeString(f.name)), "ClearRow", rowKey, nil)
+}
+
+// IncludesColumn creates a query which counts whether the given column is set in the given row.
+// The result of the query is 1 if the column is set, otherwise 0.
+func (f *Field) IncludesColumn(rowID uint64, columnID uint64) *PQLBaseQuery {
+	column := NewPQLRowQuery(fmt.Sprintf("Bitmap(col=%d)", columnID), f.index, nil)
+	return f.index.Count(f.index.Intersect(f.Row(rowID), column))
+}
+
+// IncludesColumnK creates an IncludesColumn query using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (f *Field) IncludesColumnK(rowKey string, columnKey string) *PQLBaseQuery {
+	column := NewPQLRowQuery(fmt.Sprintf("Bitmap(col='%s')", EscapePQLKey(columnKey)), f.index, nil)
+	return f.index.Count(f.index.Intersect(f.RowK(rowKey), column))
 }
 
 // TopN creates a TopN query with the given item count.
 // Returns the id and count of the top n rows (by count of columns) in the field.
+//
+// Deprecated: Use TopNFiltered with a nil row instead.
 func (f *Field) TopN(n uint64) *PQLRowQuery {
-	return NewPQLRowQuery(fmt.Sprintf("TopN(field='%s', n=%d)", f.name, n), f.index, nil)
+	return f.rowQuery(fmt.Sprintf("TopN(field='%s', n=%d)", escapeString(f.name), n), "TopN", nil, nil)
+}
+
+// TopNPaged creates a TopN query which returns enough rows for the page with the given index.
+// Pages start from 0. The TopN call does not support an offset, so the query returns
+// pageSize*(pageIndex+1) rows and the rows of the page must be selected by the client:
+//
+//	response, err := client.Query(field.TopNPaged(pageSize, pageIndex))
+//	// handle err
+//	items := response.Result().CountItems()
+//	start := pageSize * pageIndex
+//	if start > uint64(len(items)) {
+//		start = uint64(len(items))
+//	}
+//	page := items[start:]
+func (f *Field) TopNPaged(pageSize uint64, pageIndex uint64) *PQLRowQuery {
+	if pageSize == 0 {
+		return NewPQLRowQuery("", f.index, NewError("TopNPaged requires a page size greater than 0"))
+	}
+	if pageIndex >= math.MaxUint64/pageSize {
+		return NewPQLRowQuery("", f.index, NewError("TopNPaged page is out of range"))
+	}
+	return f.TopN(pageSize * (pageIndex + 1))
+}
+
+// TopNThreshold creates a TopN query with the given item count and threshold.
+// Rows with a column count less than the threshold are excluded from the result.
+// A threshold of 0 is equivalent to TopN.
+// The PQL argument is spelled treshold, which is the name the Pilosa server parses.
+func (f *Field) TopNThreshold(n uint64, threshold uint64) *PQLRowQuery {
+	if threshold == 0 {
+		return f.TopN(n)
+	}
+	return f.rowQuery(fmt.Sprintf("TopN(field='%s', n=%d, treshold=%d)",
+		escapeString(f.name), n, threshold), "TopN", nil, nil)
+}
+
+// TopNFiltered creates a TopN query with the given item count and row.
+// Only the columns in the row are counted. Pass nil for the row to count all columns.
+// The row must belong to an index with the same name as the index of the field.
+//
+// TopN is already approximate: Pilosa computes it from the ranked cache of each
+// slice, so rows with low counts which are not in the cache may be missing from
+// the result. There is no separate TopK call in PQL.
+func (f *Field) TopNFiltered(n uint64, row *PQLRowQuery) *PQLRowQuery {
+	if row == nil {
+		return f.TopN(n)
+	}
+	return f.RowTopN(n, row)
+}
+
+// RowTopNK creates a TopN query with the given item count which counts only the columns
+// in the row with the given key of filterField. filterField must be a field of the same index.
+// This will only work against a Pilosa Enterprise server.
+func (f *Field) RowTopNK(n uint64, rowKey string, filterField string) *PQLRowQuery {
+	field, err := f.index.existingField(filterField)
+	if err != nil {
+		return NewPQLRowQuery("", f.index, err)
+	}
+	return f.TopNFiltered(n, field.RowK(rowKey))
 }
 
 // RowTopN creates a TopN query with the given item count and row.
 // This variant supports customizing the row query.
+// The row must belong to an index with the same name as the index of the field,
+// e.g., a copy of the index.
+//
+// Deprecated: Use TopNFiltered instead.
 func (f *Field) RowTopN(n uint64, row *PQLRowQuery) *PQLRowQuery {
-	return NewPQLRowQuery(fmt.Sprintf("TopN(%s, field='%s', n=%d)",
-		row.serialize(), f.name, n), f.index, nil)
+	if !sameIndex(row.index, f.index) {
+		return NewPQLRowQuery("", f.index, NewError("row index mismatch in RowTopN"))
+	}
+	return f.rowQuery(fmt.Sprintf("TopN(%s, field='%s', n=%d)",
+		row.serialize(), escapeString(f.name), n), "TopN", nil, nil)
+}
+
+// sameIndex returns true if both indexes have the same name.
+// Indexes are compared by name since copies of an index, e.g., made by Index.Copy, are different structs.
+func sameIndex(a *Index, b *Index) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.name == b.name
 }
 
 // FilterFieldTopN creates a TopN query with the given item count, row, field and the filter for that field
@@ -581,6 +2279,12 @@ func (f *Field) FilterFieldTopN(n uint64, row *PQLRowQuery, field string, values
 	return f.filterFieldTopN(n, row, field, values...)
 }
 
+// FilterFieldTopNK creates a TopN query like FilterFieldTopN, using the row of this field with the given key.
+// This will only work against a Pilosa Enterprise server.
+func (f *Field) FilterFieldTopNK(n uint64, rowKey string, field string, values ...interface{}) *PQLRowQuery {
+	return f.filterFieldTopN(n, f.RowK(rowKey), field, values...)
+}
+
 func (f *Field) filterFieldTopN(n uint64, row *PQLRowQuery, field string, values ...interface{}) *PQLRowQuery {
 	if err := validateLabel(field); err != nil {
 		return NewPQLRowQuery("", f.index, err)
@@ -590,25 +2294,174 @@ func (f *Field) filterFieldTopN(n uint64, row *PQLRowQuery, field string, values
 		return NewPQLRowQuery("", f.index, err)
 	}
 	if row == nil {
-		return NewPQLRowQuery(fmt.Sprintf("TopN(field='%s', n=%d, field='%s', filters=%s)",
-			f.name, n, field, string(b)), f.index, nil)
+		return f.rowQuery(fmt.Sprintf("TopN(field='%s', n=%d, field='%s', filters=%s)",
+			escapeString(f.name), n, field, string(b)), "TopN", nil, nil)
 	}
-	return NewPQLRowQuery(fmt.Sprintf("TopN(%s, field='%s', n=%d, field='%s', filters=%s)",
-		row.serialize(), f.name, n, field, string(b)), f.index, nil)
+	return f.rowQuery(fmt.Sprintf("TopN(%s, field='%s', n=%d, field='%s', filters=%s)",
+		row.serialize(), escapeString(f.name), n, field, string(b)), "TopN", nil, nil)
 }
 
 // Range creates a Range query.
 // Similar to Row, but only returns columns which were set with timestamps between the given start and end timestamps.
 func (f *Field) Range(rowID uint64, start time.Time, end time.Time) *PQLRowQuery {
-	return NewPQLRowQuery(fmt.Sprintf("Range(row=%d, field='%s', start='%s', end='%s')",
-		rowID, f.name, start.Format(timeFormat), end.Format(timeFormat)), f.index, nil)
+	return f.rowQuery(fmt.Sprintf("Range(row=%d, field='%s', start='%s', end='%s')",
+		rowID, escapeString(f.name), start.Format(timeFormat), end.Format(timeFormat)), "Range", rowID, nil)
 }
 
 // RangeK creates a Range query using a string row key. This will only work
 // against a Pilosa Enterprise server.
 func (f *Field) RangeK(rowKey string, start time.Time, end time.Time) *PQLRowQuery {
-	return NewPQLRowQuery(fmt.Sprintf("Range(row='%s', field='%s', start='%s', end='%s')",
-		rowKey, f.name, start.Format(timeFormat), end.Format(timeFormat)), f.index, nil)
+	return f.rowQuery(fmt.Sprintf("Range(row='%s', field='%s', start='%s', end='%s')",
+		EscapePQLKey(rowKey), escapeString(f.name), start.Format(timeFormat), end.Format(timeFormat)), "Range", rowKey, nil)
+}
+
+// Clock returns the current time.
+type Clock func() time.Time
+
+// clock is used by the relative Range helpers; tests replace it with a fixed clock.
+var clock Clock = time.Now
+
+// RangeLast creates a Range query which covers the duration d until now, in UTC.
+func (f *Field) RangeLast(rowID uint64, d time.Duration) *PQLRowQuery {
+	end := clock().UTC()
+	return f.Range(rowID, end.Add(-d), end)
+}
+
+// RangeLastK creates a Range query using a string row key which covers the duration d until now, in UTC.
+// This will only work against a Pilosa Enterprise server.
+func (f *Field) RangeLastK(rowKey string, d time.Duration) *PQLRowQuery {
+	end := clock().UTC()
+	return f.RangeK(rowKey, end.Add(-d), end)
+}
+
+// RangeDate creates a Range query which covers the given day in UTC.
+// The field must have a time quantum with day or hour granularity.
+func (f *Field) RangeDate(rowID uint64, date time.Time) *PQLRowQuery {
+	if err := f.checkTimeQuantum("RangeDate", "DH"); err != nil {
+		return NewPQLRowQuery("", f.index, err)
+	}
+	date = date.UTC()
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return f.Range(rowID, start, start.AddDate(0, 0, 1).Add(-time.Minute))
+}
+
+// RangeByDate creates a Range query which covers the days from the start date to the end date
+// in UTC, including both. Months and days start from 1.
+// The query has an error if a date is not valid or if the start date is after the end date.
+func (f *Field) RangeByDate(rowID uint64, startYear, startMonth, startDay, endYear, endMonth, endDay int) *PQLRowQuery {
+	start, end, err := dateRange(startYear, startMonth, startDay, endYear, endMonth, endDay)
+	if err != nil {
+		return NewPQLRowQuery("", f.index, err)
+	}
+	return f.Range(rowID, start, end)
+}
+
+// RangeByDateK creates a Range query like RangeByDate, using a string row key.
+// This will only work against a Pilosa Enterprise server.
+func (f *Field) RangeByDateK(rowKey string, startYear, startMonth, startDay, endYear, endMonth, endDay int) *PQLRowQuery {
+	start, end, err := dateRange(startYear, startMonth, startDay, endYear, endMonth, endDay)
+	if err != nil {
+		return NewPQLRowQuery("", f.index, err)
+	}
+	return f.RangeK(rowKey, start, end)
+}
+
+// dateRange returns the start of the start date and the last minute of the end date in UTC.
+func dateRange(startYear, startMonth, startDay, endYear, endMonth, endDay int) (time.Time, time.Time, error) {
+	start, err := validDate(startYear, startMonth, startDay)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Wrap(err, "start date")
+	}
+	end, err := validDate(endYear, endMonth, endDay)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Wrap(err, "end date")
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, NewError("Range start date is after the end date")
+	}
+	return start, end.AddDate(0, 0, 1).Add(-time.Minute), nil
+}
+
+func validDate(year, month, day int) (time.Time, error) {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	// time.Date normalizes out of range values, e.g., February 30 becomes March 2
+	if month < 1 || month > 12 || date.Year() != year || int(date.Month()) != month || date.Day() != day {
+		return time.Time{}, NewError(fmt.Sprintf("Invalid date: %04d-%02d-%02d", year, month, day))
+	}
+	return date, nil
+}
+
+// RangeHour creates a Range query which covers the hour containing t in UTC.
+// The field must have a time quantum with hour granularity.
+func (f *Field) RangeHour(rowID uint64, t time.Time) *PQLRowQuery {
+	if err := f.checkTimeQuantum("RangeHour", "H"); err != nil {
+		return NewPQLRowQuery("", f.index, err)
+	}
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.UTC)
+	return f.Range(rowID, start, start.Add(time.Hour-time.Minute))
+}
+
+// RangeMonth creates a Range query which covers the given month in UTC.
+// The field must have a time quantum with month or finer granularity.
+func (f *Field) RangeMonth(rowID uint64, year int, month time.Month) *PQLRowQuery {
+	if err := f.checkTimeQuantum("RangeMonth", "MDH"); err != nil {
+		return NewPQLRowQuery("", f.index, err)
+	}
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return f.Range(rowID, start, start.AddDate(0, 1, 0).Add(-time.Minute))
+}
+
+// RangeYear creates a Range query which covers the given year in UTC.
+// The field must have a time quantum.
+func (f *Field) RangeYear(rowID uint64, year int) *PQLRowQuery {
+	if err := f.checkTimeQuantum("RangeYear", "YMDH"); err != nil {
+		return NewPQLRowQuery("", f.index, err)
+	}
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return f.Range(rowID, start, start.AddDate(1, 0, 0).Add(-time.Minute))
+}
+
+// TruncateToQuantum truncates t to the finest unit of the time quantum of the field in UTC,
+// e.g., to the start of the day for a field with TimeQuantumYearMonthDay.
+// Days and hours are truncated using TimeQuantum.Duration, years and months are
+// truncated to the start of the calendar year or month.
+// Returns ErrNoTimeQuantum if the field has no time quantum.
+func (f *Field) TruncateToQuantum(t time.Time) (time.Time, error) {
+	return truncateToQuantum(t, f.options.timeQuantum)
+}
+
+func truncateToQuantum(t time.Time, quantum TimeQuantum) (time.Time, error) {
+	d, err := quantum.Duration()
+	if err != nil {
+		return time.Time{}, err
+	}
+	t = t.UTC()
+	switch quantum[len(quantum)-1] {
+	case 'Y':
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	case 'M':
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return t.Truncate(d), nil
+}
+
+// truncateTimestamp truncates the timestamp if the field has the OptTimestampTruncate option.
+func (f *Field) truncateTimestamp(timestamp time.Time) time.Time {
+	if f.options.timestampTruncate == TimeQuantumNone {
+		return timestamp
+	}
+	// the quantum was validated by OptTimestampTruncate
+	truncated, _ := truncateToQuantum(timestamp, f.options.timestampTruncate)
+	return truncated
+}
+
+// checkTimeQuantum returns an error if the time quantum of the field has none of the given units.
+func (f *Field) checkTimeQuantum(operation string, units string) error {
+	if strings.ContainsAny(string(f.options.timeQuantum), units) {
+		return nil
+	}
+	return NewError(fmt.Sprintf("%s requires a field with one of the time quantum units %s", operation, units))
 }
 
 // SetRowAttrs creates a SetRowAttrs query.
@@ -619,8 +2472,8 @@ func (f *Field) SetRowAttrs(rowID uint64, attrs map[string]interface{}) *PQLBase
 	if err != nil {
 		return NewPQLBaseQuery("", f.index, err)
 	}
-	return NewPQLBaseQuery(fmt.Sprintf("SetRowAttrs(row=%d, field='%s', %s)",
-		rowID, f.name, attrsString), f.index, nil)
+	return f.baseQuery(fmt.Sprintf("SetRowAttrs(row=%d, field='%s', %s)",
+		rowID, escapeString(f.name), attrsString), "SetRowAttrs", rowID, nil)
 }
 
 // SetRowAttrsK creates a SetRowAttrs query using a string row key. This will
@@ -630,8 +2483,23 @@ func (f *Field) SetRowAttrsK(rowKey string, attrs map[string]interface{}) *PQLBa
 	if err != nil {
 		return NewPQLBaseQuery("", f.index, err)
 	}
-	return NewPQLBaseQuery(fmt.Sprintf("SetRowAttrs(row='%s', field='%s', %s)",
-		rowKey, f.name, attrsString), f.index, nil)
+	return f.baseQuery(fmt.Sprintf("SetRowAttrs(row='%s', field='%s', %s)",
+		EscapePQLKey(rowKey), escapeString(f.name), attrsString), "SetRowAttrs", rowKey, nil)
+}
+
+// pqlEscaper escapes the characters which would terminate a single quoted PQL string.
+var pqlEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// escapeString escapes s so it can be embedded in a single quoted PQL string.
+func escapeString(s string) string {
+	return pqlEscaper.Replace(s)
+}
+
+// EscapePQLKey escapes backslashes and single quotes in a row or column key,
+// so the key can be embedded in a single quoted PQL string.
+// The K-suffixed query methods escape their keys with this function.
+func EscapePQLKey(key string) string {
+	return escapeString(key)
 }
 
 func createAttributesString(attrs map[string]interface{}) (string, error) {
@@ -661,6 +2529,22 @@ const (
 	FieldTypeTime    FieldType = "time"
 )
 
+// IsSet returns true if the field type is FieldTypeSet.
+// Note that FieldTypeDefault is not considered a set field type.
+func (ft FieldType) IsSet() bool {
+	return ft == FieldTypeSet
+}
+
+// IsInteger returns true if the field type is FieldTypeInt.
+func (ft FieldType) IsInteger() bool {
+	return ft == FieldTypeInt
+}
+
+// IsTime returns true if the field type is FieldTypeTime.
+func (ft FieldType) IsTime() bool {
+	return ft == FieldTypeTime
+}
+
 // TimeQuantum type represents valid time quantum values time fields.
 type TimeQuantum string
 
@@ -679,6 +2563,27 @@ const (
 	TimeQuantumYearMonthDayHour TimeQuantum = "YMDH"
 )
 
+// Duration returns the duration of the finest unit of the time quantum,
+// e.g., 24 hours for TimeQuantumYearMonthDay and one hour for TimeQuantumHour.
+// Years and months do not have a fixed duration, they are approximated as 365 and 30 days.
+// Returns ErrNoTimeQuantum for TimeQuantumNone.
+func (tq TimeQuantum) Duration() (time.Duration, error) {
+	if tq == TimeQuantumNone {
+		return 0, ErrNoTimeQuantum
+	}
+	switch tq[len(tq)-1] {
+	case 'Y':
+		return 365 * 24 * time.Hour, nil
+	case 'M':
+		return 30 * 24 * time.Hour, nil
+	case 'D':
+		return 24 * time.Hour, nil
+	case 'H':
+		return time.Hour, nil
+	}
+	return 0, NewError(fmt.Sprintf("Invalid time quantum %s", string(tq)))
+}
+
 // CacheType represents cache type for a field
 type CacheType string
 
@@ -694,78 +2599,192 @@ const (
 const CacheSizeDefault = 0
 
 // LT creates a less than query.
-func (field *Field) LT(n int) *PQLRowQuery {
+func (field *Field) LT(n int64) *PQLRowQuery {
 	return field.binaryOperation("<", n)
 }
 
 // LTE creates a less than or equal query.
-func (field *Field) LTE(n int) *PQLRowQuery {
+func (field *Field) LTE(n int64) *PQLRowQuery {
 	return field.binaryOperation("<=", n)
 }
 
 // GT creates a greater than query.
-func (field *Field) GT(n int) *PQLRowQuery {
+func (field *Field) GT(n int64) *PQLRowQuery {
 	return field.binaryOperation(">", n)
 }
 
 // GTE creates a greater than or equal query.
-func (field *Field) GTE(n int) *PQLRowQuery {
+func (field *Field) GTE(n int64) *PQLRowQuery {
 	return field.binaryOperation(">=", n)
 }
 
 // Equals creates an equals query.
-func (field *Field) Equals(n int) *PQLRowQuery {
+func (field *Field) Equals(n int64) *PQLRowQuery {
 	return field.binaryOperation("==", n)
 }
 
 // NotEquals creates a not equals query.
-func (field *Field) NotEquals(n int) *PQLRowQuery {
+func (field *Field) NotEquals(n int64) *PQLRowQuery {
 	return field.binaryOperation("!=", n)
 }
 
 // NotNull creates a not equal to null query.
 func (field *Field) NotNull() *PQLRowQuery {
 	qry := fmt.Sprintf("Range(%s != null)", field.name)
-	return NewPQLRowQuery(qry, field.index, nil)
+	return field.rowQuery(qry, "Range", nil, nil)
 }
 
 // Between creates a between query.
-func (field *Field) Between(a int, b int) *PQLRowQuery {
+func (field *Field) Between(a int64, b int64) *PQLRowQuery {
 	qry := fmt.Sprintf("Range(%s >< [%d,%d])", field.name, a, b)
-	return NewPQLRowQuery(qry, field.index, nil)
+	return field.rowQuery(qry, "Range", nil, nil)
+}
+
+// BetweenExclusive creates a between query which excludes both a and b.
+// If a and b are equal, the result is empty.
+func (field *Field) BetweenExclusive(a int64, b int64) *PQLRowQuery {
+	return field.index.Intersect(field.GT(a), field.LT(b))
+}
+
+// BetweenLeftOpen creates a between query which excludes a and includes b.
+func (field *Field) BetweenLeftOpen(a int64, b int64) *PQLRowQuery {
+	return field.index.Intersect(field.GT(a), field.LTE(b))
+}
+
+// BetweenRightOpen creates a between query which includes a and excludes b.
+func (field *Field) BetweenRightOpen(a int64, b int64) *PQLRowQuery {
+	return field.index.Intersect(field.GTE(a), field.LT(b))
+}
+
+// LTInt creates a less than query.
+//
+// Deprecated: Use LT instead.
+func (field *Field) LTInt(n int) *PQLRowQuery {
+	return field.LT(int64(n))
+}
+
+// LTEInt creates a less than or equal query.
+//
+// Deprecated: Use LTE instead.
+func (field *Field) LTEInt(n int) *PQLRowQuery {
+	return field.LTE(int64(n))
+}
+
+// GTInt creates a greater than query.
+//
+// Deprecated: Use GT instead.
+func (field *Field) GTInt(n int) *PQLRowQuery {
+	return field.GT(int64(n))
+}
+
+// GTEInt creates a greater than or equal query.
+//
+// Deprecated: Use GTE instead.
+func (field *Field) GTEInt(n int) *PQLRowQuery {
+	return field.GTE(int64(n))
+}
+
+// EqualsInt creates an equals query.
+//
+// Deprecated: Use Equals instead.
+func (field *Field) EqualsInt(n int) *PQLRowQuery {
+	return field.Equals(int64(n))
+}
+
+// NotEqualsInt creates a not equals query.
+//
+// Deprecated: Use NotEquals instead.
+func (field *Field) NotEqualsInt(n int) *PQLRowQuery {
+	return field.NotEquals(int64(n))
+}
+
+// BetweenInt creates a between query.
+//
+// Deprecated: Use Between instead.
+func (field *Field) BetweenInt(a int, b int) *PQLRowQuery {
+	return field.Between(int64(a), int64(b))
 }
 
 // Sum creates a sum query.
+// Pass nil for the row to sum the values of all columns.
 func (field *Field) Sum(row *PQLRowQuery) *PQLBaseQuery {
 	return field.valQuery("Sum", row)
 }
 
 // Min creates a min query.
+// Pass nil for the row to use the values of all columns.
 func (field *Field) Min(row *PQLRowQuery) *PQLBaseQuery {
 	return field.valQuery("Min", row)
 }
 
-// Max creates a min query.
+// Max creates a max query.
+// Pass nil for the row to use the values of all columns.
 func (field *Field) Max(row *PQLRowQuery) *PQLBaseQuery {
 	return field.valQuery("Max", row)
 }
 
+// Distinct creates a Distinct query.
+// Distinct returns the distinct values of the field.
+// This requires a Pilosa server which supports the Distinct call.
+func (field *Field) Distinct() *PQLBaseQuery {
+	return field.valQuery("Distinct", nil)
+}
+
+// DistinctFiltered creates a Distinct query which considers only the columns in the row.
+// Pass nil for the row to use all columns.
+// This requires a Pilosa server which supports the Distinct call.
+func (field *Field) DistinctFiltered(row *PQLRowQuery) *PQLBaseQuery {
+	return field.valQuery("Distinct", row)
+}
+
+// ValQuery creates a query for the given call which has the field as an argument,
+// e.g., Sum(Bitmap(row=1, field='f'), field='price') for op Sum.
+// Pass nil for the row to use all columns.
+// It can be used for aggregate calls which have no method, such as server extensions.
+// op must be a valid call name, containing only letters, digits and underscores and
+// starting with a letter. Otherwise the query has an error.
+func (field *Field) ValQuery(op string, row *PQLRowQuery) *PQLBaseQuery {
+	if !validCallName(op) {
+		return NewPQLBaseQuery("", field.index, NewError(fmt.Sprintf("Invalid call name: '%s'", op)))
+	}
+	return field.valQuery(op, row)
+}
+
 // SetIntValue creates a SetValue query.
-func (field *Field) SetIntValue(columnID uint64, value int) *PQLBaseQuery {
+// SetValue replaces the stored value. Pilosa has no PQL call for atomically
+// incrementing or decrementing an integer field, so read-modify-write
+// updates must be coordinated by the caller.
+func (field *Field) SetIntValue(columnID uint64, value int64) *PQLBaseQuery {
 	qry := fmt.Sprintf("SetValue(col=%d, %s=%d)", columnID, field.name, value)
-	return NewPQLBaseQuery(qry, field.index, nil)
+	return field.baseQuery(qry, "SetValue", nil, columnID)
 }
 
 // SetIntValueK creates a SetValue query using a string column key. This will
 // only work against a Pilosa Enterprise server.
-func (field *Field) SetIntValueK(columnKey string, value int) *PQLBaseQuery {
-	qry := fmt.Sprintf("SetValue(col='%s', %s=%d)", columnKey, field.name, value)
-	return NewPQLBaseQuery(qry, field.index, nil)
+func (field *Field) SetIntValueK(columnKey string, value int64) *PQLBaseQuery {
+	qry := fmt.Sprintf("SetValue(col='%s', %s=%d)", EscapePQLKey(columnKey), field.name, value)
+	return field.baseQuery(qry, "SetValue", nil, columnKey)
+}
+
+// SetIntValueInt creates a SetValue query.
+//
+// Deprecated: Use SetIntValue instead.
+func (field *Field) SetIntValueInt(columnID uint64, value int) *PQLBaseQuery {
+	return field.SetIntValue(columnID, int64(value))
 }
 
-func (field *Field) binaryOperation(op string, n int) *PQLRowQuery {
+// SetIntValueKInt creates a SetValue query using a string column key.
+//
+// Deprecated: Use SetIntValueK instead.
+func (field *Field) SetIntValueKInt(columnKey string, value int) *PQLBaseQuery {
+	return field.SetIntValueK(columnKey, int64(value))
+}
+
+// binaryOperation embeds the field name without quotes, so it relies on
+// the name being validated when the field was created.
+func (field *Field) binaryOperation(op string, n int64) *PQLRowQuery {
 	qry := fmt.Sprintf("Range(%s %s %d)", field.name, op, n)
-	return NewPQLRowQuery(qry, field.index, nil)
+	return field.rowQuery(qry, "Range", nil, nil)
 }
 
 func (field *Field) valQuery(op string, row *PQLRowQuery) *PQLBaseQuery {
@@ -773,8 +2792,20 @@ func (field *Field) valQuery(op string, row *PQLRowQuery) *PQLBaseQuery {
 	if row != nil {
 		rowStr = fmt.Sprintf("%s, ", row.serialize())
 	}
-	qry := fmt.Sprintf("%s(%sfield='%s')", op, rowStr, field.name)
-	return NewPQLBaseQuery(qry, field.index, nil)
+	qry := fmt.Sprintf("%s(%sfield='%s')", op, rowStr, escapeString(field.name))
+	return field.baseQuery(qry, op, nil, nil)
+}
+
+func validCallName(name string) bool {
+	if name == "" || !(name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z') {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isPQLNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 func encodeMap(m map[string]interface{}) string {
@@ -33,13 +33,17 @@
 package pilosa
 
 import (
-	"errors"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 var schema = NewSchema()
@@ -71,10 +75,109 @@ func TestSchemaDiff(t *testing.T) {
 	targetIndex2, _ := targetDiff12.Index("diff-index2")
 	targetIndex2.Field("field2-1")
 
-	diff12 := schema1.diff(schema2)
+	diff12 := schema1.DiffSchema(schema2)
 	if !reflect.DeepEqual(targetDiff12, diff12) {
 		t.Fatalf("The diff must be correctly calculated")
 	}
+	if diff := schema1.DiffSchema(nil); !reflect.DeepEqual(schema1, diff) {
+		t.Fatalf("The diff with a nil schema must be a copy of the schema")
+	}
+	if diff := schema1.DiffSchema(NewSchema()); !reflect.DeepEqual(schema1, diff) {
+		t.Fatalf("The diff with an empty schema must be a copy of the schema")
+	}
+}
+
+func TestSchemaSyncPlan(t *testing.T) {
+	local := NewSchema()
+	index1, _ := local.Index("plan-index1")
+	index1.Field("field1-1")
+	index1.Field("field1-2")
+	index2, _ := local.Index("plan-index2")
+	index2.Field("field2-1")
+
+	remote := NewSchema()
+	remoteIndex1, _ := remote.Index("plan-index1")
+	remoteIndex1.Field("field1-1")
+	remoteIndex1.Field("remote-field")
+	remoteIndex3, _ := remote.Index("plan-index3")
+	remoteIndex3.Field("field3-1")
+
+	target := []SyncAction{
+		{Type: SyncActionCreate, Index: "plan-index1", Field: "field1-2"},
+		{Type: SyncActionCreate, Index: "plan-index2"},
+		{Type: SyncActionCreate, Index: "plan-index2", Field: "field2-1"},
+		{Type: SyncActionDelete, Index: "plan-index1", Field: "remote-field"},
+		{Type: SyncActionDelete, Index: "plan-index3"},
+	}
+	plan := local.SyncPlan(remote)
+	if !reflect.DeepEqual(target, plan) {
+		t.Fatalf("%v != %v", target, plan)
+	}
+
+	targetText := "+ create field plan-index1/field1-2\n" +
+		"+ create index plan-index2\n" +
+		"+ create field plan-index2/field2-1\n" +
+		"- delete field plan-index1/remote-field\n" +
+		"- delete index plan-index3\n"
+	if text := local.FormatPlan(plan); targetText != text {
+		t.Fatalf("%s != %s", targetText, text)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := local.WritePlan(buf, plan[3:4], true); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "\x1b[31m- delete field plan-index1/remote-field\x1b[0m\n" {
+		t.Fatalf("unexpected colored plan: %q", buf.String())
+	}
+}
+
+func TestSchemaDiffKeys(t *testing.T) {
+	local, err := NewSchemaWith(OptSchemaIndex("diff-index1"), OptSchemaIndex("diff-index2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local.indexes["diff-index1"].Field("field1-2")
+	local.indexes["diff-index1"].Field("field1-1")
+	local.indexes["diff-index2"].Field("field2-1")
+	remote, err := NewSchemaWith(OptSchemaIndex("diff-index1"), OptSchemaIndex("diff-index3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.indexes["diff-index1"].Field("remote-field")
+	remote.indexes["diff-index3"].Field("field3-1")
+
+	addedIndexes, removedIndexes, addedFields, removedFields := local.DiffKeys(remote)
+	if !reflect.DeepEqual([]string{"diff-index2"}, addedIndexes) {
+		t.Fatalf("wrong added indexes: %v", addedIndexes)
+	}
+	if !reflect.DeepEqual([]string{"diff-index3"}, removedIndexes) {
+		t.Fatalf("wrong removed indexes: %v", removedIndexes)
+	}
+	targetAdded := map[string][]string{
+		"diff-index1": {"field1-1", "field1-2"},
+		"diff-index2": {"field2-1"},
+	}
+	if !reflect.DeepEqual(targetAdded, addedFields) {
+		t.Fatalf("wrong added fields: %v", addedFields)
+	}
+	targetRemoved := map[string][]string{
+		"diff-index1": {"remote-field"},
+	}
+	if !reflect.DeepEqual(targetRemoved, removedFields) {
+		t.Fatalf("wrong removed fields: %v", removedFields)
+	}
+
+	addedIndexes, removedIndexes, addedFields, removedFields = local.DiffKeys(local)
+	if len(addedIndexes) != 0 || len(removedIndexes) != 0 || len(addedFields) != 0 || len(removedFields) != 0 {
+		t.Fatalf("diff with itself should be empty")
+	}
+}
+
+func TestSchemaSyncPlanEmpty(t *testing.T) {
+	if plan := schema.SyncPlan(schema); len(plan) != 0 {
+		t.Fatalf("plan should be empty: %v", plan)
+	}
 }
 
 func TestSchemaIndexes(t *testing.T) {
@@ -134,12 +237,224 @@ func TestIndexCopy(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	copiedIndex := index.copy()
+	copiedIndex := index.Copy()
 	if !reflect.DeepEqual(index, copiedIndex) {
 		t.Fatalf("copied index should be equivalent")
 	}
 }
 
+func TestSchemaIndexByName(t *testing.T) {
+	schema1 := NewSchema()
+	if _, err := schema1.Index("by-name-index"); err != nil {
+		t.Fatal(err)
+	}
+	index, ok := schema1.IndexByName("by-name-index")
+	if !ok || index.Name() != "by-name-index" {
+		t.Fatalf("index should be found")
+	}
+	if _, err := index.Field("by-name-field"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := schema1.Indexes()["by-name-index"].Fields()["by-name-field"]; !ok {
+		t.Fatalf("field added through the returned index should be in the schema")
+	}
+	field, ok := index.FieldByName("by-name-field")
+	if !ok || field.Name() != "by-name-field" {
+		t.Fatalf("field should be found")
+	}
+	field.options.cacheSize = 42
+	if schema1.indexes["by-name-index"].fields["by-name-field"].options.cacheSize != 42 {
+		t.Fatalf("changes to the returned field should be in the index")
+	}
+	if index, ok := schema1.IndexByName("no-such-index"); ok || index != nil {
+		t.Fatalf("index should not be found")
+	}
+	if field, ok := index.FieldByName("no-such-field"); ok || field != nil {
+		t.Fatalf("field should not be found")
+	}
+}
+
+func TestNewSchemaWith(t *testing.T) {
+	existing, err := NewIndex("existing", OptIndexKeys(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := existing.Field("existing-field"); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := NewSchemaWith(
+		OptSchemaIndex("plain"),
+		OptSchemaIndex("with-options", OptIndexKeys(true), OptIndexTrackExistence(true)),
+		OptSchemaExistingIndex(existing),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes := schema.Indexes()
+	if len(indexes) != 3 {
+		t.Fatalf("schema should have 3 indexes, got %d", len(indexes))
+	}
+	if indexes["plain"].Options() != (IndexOptions{}) {
+		t.Fatalf("plain index should have default options: %#v", indexes["plain"].Options())
+	}
+	if !indexes["with-options"].Options().Keys() || !indexes["with-options"].Options().TrackExistence() {
+		t.Fatalf("index options were not set: %#v", indexes["with-options"].Options())
+	}
+	copied := schema.indexes["existing"]
+	if copied == existing || !copied.Options().Keys() {
+		t.Fatalf("existing index should be copied with its options")
+	}
+	if _, ok := copied.Fields()["existing-field"]; !ok {
+		t.Fatalf("existing index fields should be copied")
+	}
+
+	schema, err = NewSchemaWith()
+	if err != nil || len(schema.Indexes()) != 0 {
+		t.Fatalf("schema without options should be empty")
+	}
+}
+
+func TestNewSchemaWithErrors(t *testing.T) {
+	existing, err := NewIndex("existing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		options []SchemaOption
+		err     error
+	}{
+		{[]SchemaOption{OptSchemaIndex("$invalid$")}, ErrInvalidIndexName},
+		{[]SchemaOption{OptSchemaIndex("valid", nil)}, ErrInvalidIndexOption},
+		{[]SchemaOption{OptSchemaExistingIndex(nil)}, ErrInvalidSchemaOption},
+		{[]SchemaOption{OptSchemaIndex("existing"), OptSchemaExistingIndex(existing)}, ErrIndexExists},
+		{[]SchemaOption{nil}, ErrInvalidSchemaOption},
+	}
+	for i, test := range tests {
+		schema, err := NewSchemaWith(test.options...)
+		if err != test.err {
+			t.Fatalf("%d: expected %v, got %v", i, test.err, err)
+		}
+		if schema != nil {
+			t.Fatalf("%d: schema should be nil", i)
+		}
+	}
+}
+
+func TestIndexCopyInto(t *testing.T) {
+	index, err := NewIndex("copy-into-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = index.Field("copy-into-field", OptFieldInt(0, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema1 := NewSchema()
+	copiedIndex, err := index.CopyInto(schema1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copiedIndex == index {
+		t.Fatalf("CopyInto should return a copy")
+	}
+	if idx, _ := schema1.Index("copy-into-index"); idx != copiedIndex {
+		t.Fatalf("copy should be registered in the schema")
+	}
+	copiedIndex.fields["copy-into-field"].options.max = 1000
+	_, err = copiedIndex.Field("another-field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.fields) != 1 {
+		t.Fatalf("original index should have 1 field, got %d", len(index.fields))
+	}
+	if index.fields["copy-into-field"].options.max != 100 {
+		t.Fatalf("original field options should not change")
+	}
+
+	if _, err := index.CopyInto(schema1); err != ErrIndexExists {
+		t.Fatalf("should have failed with ErrIndexExists: %v", err)
+	}
+}
+
+func TestSchemaCopy(t *testing.T) {
+	schema1 := NewSchema()
+	index, err := schema1.Index("copy-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = index.Field("copy-field", OptFieldInt(0, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := schema1.Copy()
+	if !reflect.DeepEqual(schema1, copied) {
+		t.Fatalf("copied schema should be equivalent")
+	}
+
+	copiedIndex, err := copied.Index("copy-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	copiedField, err := copiedIndex.Field("another-field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copiedField.index != copiedIndex {
+		t.Fatalf("copied field should belong to the copied index")
+	}
+	copiedIndex.fields["copy-field"].options.max = 1000
+	_, err = copied.Index("another-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(schema1.indexes) != 1 {
+		t.Fatalf("original schema should have 1 index, got %d", len(schema1.indexes))
+	}
+	if len(index.fields) != 1 {
+		t.Fatalf("original index should have 1 field, got %d", len(index.fields))
+	}
+	if index.fields["copy-field"].options.max != 100 {
+		t.Fatalf("original field options should not change")
+	}
+}
+
+func TestIndexNameCannotBeChanged(t *testing.T) {
+	schema1 := NewSchema()
+	index, err := schema1.Index("validated-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := index.Field("validated-field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// names are only accessible through getters
+	for _, v := range []interface{}{*index, *field} {
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			if typ.Field(i).PkgPath == "" {
+				t.Fatalf("%s.%s should not be exported", typ.Name(), typ.Field(i).Name)
+			}
+		}
+	}
+	// modifying a copy doesn't change the name in the schema
+	indexCopy := schema1.Indexes()["validated-index"]
+	indexCopy.name = "$invalid$"
+	fieldCopy := index.Fields()["validated-field"]
+	fieldCopy.name = "$invalid$"
+	if index.Name() != "validated-index" {
+		t.Fatalf("index name should not be changed")
+	}
+	if field.Name() != "validated-field" {
+		t.Fatalf("field name should not be changed")
+	}
+	comparePQL(t,
+		"Bitmap(row=1, field='validated-field')",
+		field.Row(1))
+}
+
 func TestIndexFields(t *testing.T) {
 	schema1 := NewSchema()
 	index11, _ := schema1.Index("diff-index1")
@@ -158,12 +473,45 @@ func TestIndexFields(t *testing.T) {
 func TestIndexToString(t *testing.T) {
 	schema1 := NewSchema()
 	index, _ := schema1.Index("test-index")
-	target := fmt.Sprintf(`&pilosa.Index{name:"test-index", fields:map[string]*pilosa.Field{}}`)
+	target := fmt.Sprintf(`&pilosa.Index{name:"test-index", fields:map[string]*pilosa.Field{}, options:pilosa.IndexOptions{keys:false, trackExistence:false}}`)
 	if target != index.String() {
 		t.Fatalf("%s != %s", target, index.String())
 	}
 }
 
+func TestIndexOptions(t *testing.T) {
+	schema1 := NewSchema()
+	index, err := schema1.Index("options-index", OptIndexKeys(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !index.Options().Keys() || index.Options().TrackExistence() {
+		t.Fatalf("index options were not set: %v", index.Options())
+	}
+	if !index.Copy().Options().Keys() {
+		t.Fatalf("copied index should preserve options")
+	}
+	if !schema1.Copy().indexes["options-index"].Options().Keys() {
+		t.Fatalf("copied schema should preserve index options")
+	}
+	target := `{"options":{"keys":true}}`
+	if target != index.Options().String() {
+		t.Fatalf("%s != %s", target, index.Options().String())
+	}
+
+	index, err = NewIndex("options-index2", OptIndexKeys(true), OptIndexTrackExistence(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target = `{"options":{"keys":true,"trackExistence":true}}`
+	if target != index.Options().String() {
+		t.Fatalf("%s != %s", target, index.Options().String())
+	}
+	if _, err := NewIndex("options-index3", nil); err != ErrInvalidIndexOption {
+		t.Fatalf("Should have failed with ErrInvalidIndexOption: %v", err)
+	}
+}
+
 func TestField(t *testing.T) {
 	field1, err := sampleIndex.Field("nonexistent-field")
 	if err != nil {
@@ -181,6 +529,79 @@ func TestField(t *testing.T) {
 	}
 }
 
+func TestFieldOptionsConflict(t *testing.T) {
+	index, err := NewIndex("conflict-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := index.Field("price", OptFieldInt(0, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := index.Field("price", OptFieldInt(0, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != field {
+		t.Fatalf("calling index.Field with the same options should return the same field")
+	}
+	if _, err := index.Field("price", OptFieldInt(0, 1000)); errors.Cause(err) != ErrFieldOptionsConflict {
+		t.Fatalf("Expected ErrFieldOptionsConflict, got %v", err)
+	}
+	if _, err := index.Field("price", OptFieldTime(TimeQuantumDay)); errors.Cause(err) != ErrFieldOptionsConflict {
+		t.Fatalf("Expected ErrFieldOptionsConflict, got %v", err)
+	}
+	if same, err := index.Field("price"); err != nil || same != field {
+		t.Fatalf("calling index.Field without options should return the existing field: %v", err)
+	}
+
+	ranked, err := index.Field("ranked", OptFieldSet(CacheTypeRanked, 50000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, err := index.Field("ranked", OptFieldSet(CacheTypeDefault, CacheSizeDefault)); err != nil || same != ranked {
+		t.Fatalf("default cache options should not conflict: %v", err)
+	}
+	if _, err := index.Field("ranked", OptFieldSet(CacheTypeLRU, 50000)); errors.Cause(err) != ErrFieldOptionsConflict {
+		t.Fatalf("Expected ErrFieldOptionsConflict, got %v", err)
+	}
+	implicit, err := index.Field("implicit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, err := index.Field("implicit", OptFieldInt(0, 10)); err != nil || same != implicit {
+		t.Fatalf("options which were not set for the field should not conflict: %v", err)
+	}
+	truncated, err := index.Field("truncated", OptFieldTime(TimeQuantumYearMonthDay), OptTimestampTruncate(TimeQuantumDay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, err := index.Field("truncated", OptTimestampTruncate(TimeQuantumDay)); err != nil || same != truncated {
+		t.Fatalf("the same timestamp truncation should not conflict: %v", err)
+	}
+	if _, err := index.Field("truncated", OptTimestampTruncate(TimeQuantumHour)); errors.Cause(err) != ErrFieldOptionsConflict {
+		t.Fatalf("Expected ErrFieldOptionsConflict, got %v", err)
+	}
+
+	reused, err := index.FieldOrCreate("price", OptFieldInt(0, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reused != field || reused.options.max != 100 {
+		t.Fatalf("FieldOrCreate should return the existing field: %v", reused.options)
+	}
+	created, err := index.FieldOrCreate("quantity", OptFieldInt(0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.options.fieldType != FieldTypeInt || created.options.max != 10 {
+		t.Fatalf("FieldOrCreate should create the field with the given options: %v", created.options)
+	}
+	if _, err := index.FieldOrCreate("$invalid$"); err == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
 func TestFieldCopy(t *testing.T) {
 	options := &FieldOptions{
 		timeQuantum: TimeQuantumMonthDayHour,
@@ -208,17 +629,159 @@ func TestNewFieldWithInvalidName(t *testing.T) {
 	}
 }
 
+func TestNewFieldWithSpecialCharacters(t *testing.T) {
+	index, err := NewIndex("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a'b", "a)b", "a.b", "a*", "a+b", "a[b]", "a\\b"} {
+		if _, err := index.Field(name); err == nil {
+			t.Fatalf("Creating field %s should fail", name)
+		}
+	}
+	field, err := index.Field("a-b_c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t,
+		"Bitmap(row=1, field='a-b_c1')",
+		field.Row(1))
+}
+
+func TestEscapeString(t *testing.T) {
+	targets := map[string]string{
+		"foo":   "foo",
+		"it's":  "it\\'s",
+		"a\\b":  "a\\\\b",
+		"\\'":   "\\\\\\'",
+		"a)b(c": "a)b(c",
+		"":      "",
+	}
+	for s, target := range targets {
+		if escaped := escapeString(s); escaped != target {
+			t.Fatalf("%s != %s", target, escaped)
+		}
+		if escaped := EscapePQLKey(s); escaped != target {
+			t.Fatalf("%s != %s", target, escaped)
+		}
+	}
+}
+
+func TestKeyMethodsEscapeKeys(t *testing.T) {
+	start := time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+	comparePQL(t,
+		`Bitmap(row='it\'s', field='sample-field')`,
+		sampleField.RowK("it's"))
+	comparePQL(t,
+		`SetBit(row='it\'s', field='sample-field', col='a\\b')`,
+		sampleField.SetBitK("it's", `a\b`))
+	comparePQL(t,
+		`SetBit(row='it\'s', field='sample-field', col='a\\b', timestamp='1970-01-01T00:00')`,
+		sampleField.SetBitTimestampK("it's", `a\b`, start))
+	comparePQL(t,
+		`ClearBit(row='it\'s', field='sample-field', col='a\\b')`,
+		sampleField.ClearBitK("it's", `a\b`))
+	comparePQL(t,
+		`Range(row='it\'s', field='sample-field', start='1970-01-01T00:00', end='1970-01-01T00:00')`,
+		sampleField.RangeK("it's", start, start))
+	comparePQL(t,
+		`SetRowAttrs(row='it\'s', field='sample-field', x=1)`,
+		sampleField.SetRowAttrsK("it's", map[string]interface{}{"x": 1}))
+	comparePQL(t,
+		`SetValue(col='it\'s', collaboration=5)`,
+		collabField.SetIntValueK("it's", 5))
+	comparePQL(t,
+		`Count(Intersect(Bitmap(row='a\\b', field='sample-field'), Bitmap(col='it\'s')))`,
+		sampleField.IncludesColumnK(`a\b`, "it's"))
+}
+
 func TestFieldToString(t *testing.T) {
 	schema1 := NewSchema()
 	index, _ := schema1.Index("test-index")
 	field, _ := index.Field("test-field")
-	target := fmt.Sprintf(`&pilosa.Field{name:"test-field", index:(*pilosa.Index)(%p), options:(*pilosa.FieldOptions)(%p)}`,
+	target := fmt.Sprintf(`&pilosa.Field{name:"test-field", index:(*pilosa.Index)(%p), options:(*pilosa.FieldOptions)(%p), logger:pilosa.QueryLogger(nil)}`,
 		field.index, field.options)
 	if target != field.String() {
 		t.Fatalf("%s != %s", target, field.String())
 	}
 }
 
+type testQueryLogger struct {
+	entries [][]interface{}
+}
+
+func (l *testQueryLogger) Debug(msg string, args ...interface{}) {
+	l.entries = append(l.entries, append([]interface{}{msg}, args...))
+}
+
+func TestFieldWithName(t *testing.T) {
+	field, err := sampleIndex.Field("with-name-field", OptFieldInt(-10, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed, err := field.WithName("renamed-field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name() != "renamed-field" || field.Name() != "with-name-field" {
+		t.Fatalf("wrong names: %s, %s", renamed.Name(), field.Name())
+	}
+	if renamed.options == field.options || !reflect.DeepEqual(renamed.options, field.options) {
+		t.Fatalf("renamed field should have a copy of the options: %v", renamed.options)
+	}
+	if _, ok := sampleIndex.FieldByName("renamed-field"); ok {
+		t.Fatalf("renamed field should not be added to the index")
+	}
+	comparePQL(t, "Range(renamed-field > 5)", renamed.GT(5))
+	if _, err := field.WithName("$invalid$"); err != ErrInvalidFieldName {
+		t.Fatalf("Expected ErrInvalidFieldName, got %v", err)
+	}
+}
+
+func TestFieldWithLogger(t *testing.T) {
+	logger := &testQueryLogger{}
+	field := sampleField.WithLogger(logger)
+	comparePQL(t, "SetBit(row=1, field='sample-field', col=2)", field.SetBit(1, 2))
+	field.ClearBitK("foo", "bar")
+	target := [][]interface{}{
+		{"pilosa query built", "field_name", "sample-field", "query_type", "SetBit", "row_id", uint64(1), "col_id", uint64(2)},
+		{"pilosa query built", "field_name", "sample-field", "query_type", "ClearBit", "row_id", "foo", "col_id", "bar"},
+	}
+	if !reflect.DeepEqual(target, logger.entries) {
+		t.Fatalf("%v != %v", target, logger.entries)
+	}
+	if sampleField.logger != nil {
+		t.Fatalf("WithLogger should not modify the original field")
+	}
+	if field.index != sampleIndex {
+		t.Fatalf("field with logger should belong to the same index")
+	}
+	field.WithLogger(nil).SetBit(1, 2)
+	if len(logger.entries) != 2 {
+		t.Fatalf("field without logger should not log")
+	}
+}
+
+func TestFieldWithLoggerNonBitQueries(t *testing.T) {
+	logger := &testQueryLogger{}
+	field := sampleField.WithLogger(logger)
+	comparePQL(t, "Bitmap(row=1, field='sample-field')", field.Row(1))
+	field.TopN(5)
+	field.Sum(nil)
+	field.SetIntValueK("foo", 10)
+	field.SetBitRange(3, 10, 12)
+	target := [][]interface{}{
+		{"pilosa query built", "field_name", "sample-field", "query_type", "Bitmap", "row_id", uint64(1), "col_id", nil},
+		{"pilosa query built", "field_name", "sample-field", "query_type", "TopN", "row_id", nil, "col_id", nil},
+		{"pilosa query built", "field_name", "sample-field", "query_type", "Sum", "row_id", nil, "col_id", nil},
+		{"pilosa query built", "field_name", "sample-field", "query_type", "SetValue", "row_id", nil, "col_id", "foo"},
+		{"pilosa query built", "field_name", "sample-field", "query_type", "SetBitRange", "row_id", uint64(3), "col_id", nil},
+	}
+	if !reflect.DeepEqual(target, logger.entries) {
+		t.Fatalf("%v != %v", target, logger.entries)
+	}
+}
+
 func TestFieldSetType(t *testing.T) {
 	schema1 := NewSchema()
 	index, _ := schema1.Index("test-index")
@@ -238,6 +801,30 @@ func TestRow(t *testing.T) {
 		collabField.Row(10))
 }
 
+func TestRowLargeRowID(t *testing.T) {
+	// %d formats uint64 values above math.MaxInt64 correctly
+	comparePQL(t,
+		"Bitmap(row=9223372036854775808, field='sample-field')",
+		sampleField.Row(math.MaxInt64+1))
+	comparePQL(t,
+		"Bitmap(row=18446744073709551615, field='sample-field')",
+		sampleField.Row(math.MaxUint64))
+}
+
+func TestRowInt(t *testing.T) {
+	field, err := sampleIndex.Field("row-int-field", OptFieldInt(-10, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t, "Bitmap(row=-5, field='row-int-field')", field.RowInt(-5))
+	comparePQL(t, "Bitmap(row=0, field='row-int-field')", field.RowInt(0))
+	comparePQL(t, "Bitmap(row=5, field='row-int-field')", field.RowInt(5))
+	comparePQL(t, "Bitmap(row=-9223372036854775808, field='row-int-field')", field.RowInt(math.MinInt64))
+	if sampleField.RowInt(-5).Error() == nil {
+		t.Fatalf("RowInt on a non-int field should fail")
+	}
+}
+
 func TestRowK(t *testing.T) {
 	comparePQL(t,
 		"Bitmap(row='myrow', field='sample-field')",
@@ -253,14 +840,167 @@ func TestSetBit(t *testing.T) {
 		collabField.SetBit(10, 20))
 }
 
-func TestSetBitK(t *testing.T) {
+func TestSetBitRange(t *testing.T) {
 	comparePQL(t,
-		"SetBit(row='myrow', field='sample-field', col='mycol')",
-		sampleField.SetBitK("myrow", "mycol"))
-}
-
-func TestSetBitTimestamp(t *testing.T) {
-	timestamp := time.Date(2017, time.April, 24, 12, 14, 0, 0, time.UTC)
+		"SetBit(row=5, field='sample-field', col=10)SetBit(row=5, field='sample-field', col=11)SetBit(row=5, field='sample-field', col=12)",
+		sampleField.SetBitRange(5, 10, 12))
+	comparePQL(t,
+		"SetBit(row=5, field='sample-field', col=10)",
+		sampleField.SetBitRange(5, 10, 10))
+	comparePQL(t,
+		"SetBit(row=5, field='sample-field', col=18446744073709551615)",
+		sampleField.SetBitRange(5, math.MaxUint64, math.MaxUint64))
+	if sampleField.SetBitRange(5, 12, 10).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+
+	defer func(size uint64) { MaxBatchSize = size }(MaxBatchSize)
+	MaxBatchSize = 3
+	if q := sampleField.SetBitRange(5, 10, 12); q.Error() != nil || q.Count() != 3 {
+		t.Fatalf("SetBitRange with MaxBatchSize columns should succeed: %v", q.Error())
+	}
+	if sampleField.SetBitRange(5, 10, 13).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if sampleField.SetBitRange(5, 0, math.MaxUint64).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestBatchQuerySized(t *testing.T) {
+	q1 := sampleField.SetBit(1, 10)
+	q2 := sampleField.SetBit(2, 20)
+	q3 := sampleField.SetBit(3, 30)
+	size := len(q1.serialize())
+	tests := []struct {
+		maxBytes int
+		counts   []int
+	}{
+		{size, []int{1, 1, 1}},
+		{2*size - 1, []int{1, 1, 1}},
+		{2 * size, []int{2, 1}},
+		{3 * size, []int{3}},
+		{10 * size, []int{3}},
+	}
+	for _, test := range tests {
+		batches, err := sampleIndex.BatchQuerySized(test.maxBytes, q1, q2, q3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := []int{}
+		pql := ""
+		for _, batch := range batches {
+			if batch.EstimatedByteSize() > test.maxBytes {
+				t.Fatalf("%d: batch is too large: %d", test.maxBytes, batch.EstimatedByteSize())
+			}
+			counts = append(counts, batch.Count())
+			pql += batch.serialize()
+		}
+		if !reflect.DeepEqual(test.counts, counts) {
+			t.Fatalf("%d: %v != %v", test.maxBytes, test.counts, counts)
+		}
+		if pql != q1.serialize()+q2.serialize()+q3.serialize() {
+			t.Fatalf("%d: queries should keep their order", test.maxBytes)
+		}
+	}
+
+	batches, err := sampleIndex.BatchQuerySized(size)
+	if err != nil || len(batches) != 0 {
+		t.Fatalf("no queries should give no batches")
+	}
+	if _, err := sampleIndex.BatchQuerySized(size-1, q1); err == nil {
+		t.Fatalf("query larger than maxBytes should fail")
+	}
+	if _, err := sampleIndex.BatchQuerySized(0, q1); err == nil {
+		t.Fatalf("maxBytes 0 should fail")
+	}
+	if _, err := sampleIndex.BatchQuerySized(size, q1, sampleIndex.Xor(b1)); err == nil {
+		t.Fatalf("query error should be returned")
+	}
+}
+
+func TestQueryPlan(t *testing.T) {
+	plan := QueryPlan{b1}.Append(sampleField.SetBit(1, 2)).Append(b2)
+	comparePQL(t,
+		"Bitmap(row=10, field='sample-field')SetBit(row=1, field='sample-field', col=2)Bitmap(row=20, field='sample-field')",
+		plan.ToBatchQuery(sampleIndex))
+
+	rows := plan.Filter(func(q PQLQuery) bool { return q.QueryType() == QueryTypeRow })
+	comparePQL(t,
+		"Bitmap(row=10, field='sample-field')Bitmap(row=20, field='sample-field')",
+		rows.ToBatchQuery(sampleIndex))
+
+	counts := rows.Map(func(q PQLQuery) PQLQuery { return sampleIndex.Count(q.(*PQLRowQuery)) })
+	comparePQL(t,
+		"Count(Bitmap(row=10, field='sample-field'))Count(Bitmap(row=20, field='sample-field'))",
+		counts.ToBatchQuery(sampleIndex))
+
+	if len(plan) != 3 || len(rows) != 2 || plan[2] != b2 {
+		t.Fatalf("plan methods should not modify the plan")
+	}
+	comparePQL(t, "", QueryPlan(nil).ToBatchQuery(sampleIndex))
+}
+
+func TestQueryPlanAppendDoesNotShare(t *testing.T) {
+	base := make(QueryPlan, 1, 10)
+	base[0] = b1
+	p1 := base.Append(b2)
+	p2 := base.Append(b3)
+	if p1[1] != b2 || p2[1] != b3 {
+		t.Fatalf("appended plans should not share storage")
+	}
+}
+
+func TestQueryPlanError(t *testing.T) {
+	q := QueryPlan{b1, sampleIndex.Xor(b1)}.ToBatchQuery(sampleIndex)
+	if q.Error() == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestCell(t *testing.T) {
+	cell := Cell{Row: 5, Col: 10}
+	comparePQL(t, "SetBit(row=5, field='sample-field', col=10)", sampleField.SetCell(cell))
+	comparePQL(t, "ClearBit(row=5, field='sample-field', col=10)", sampleField.ClearCell(cell))
+	comparePQL(t,
+		"SetBit(row=5, field='sample-field', col=10)SetBit(row=1, field='sample-field', col=2)",
+		sampleField.SetCells([]Cell{cell, {Row: 1, Col: 2}}))
+	comparePQL(t, "", sampleField.SetCells(nil))
+}
+
+func TestSetBitsFromMap(t *testing.T) {
+	columnIDs := []uint64{30, 10}
+	comparePQL(t,
+		"SetBit(row=1, field='sample-field', col=5)SetBit(row=2, field='sample-field', col=10)SetBit(row=2, field='sample-field', col=30)",
+		sampleField.SetBitsFromMap(map[uint64][]uint64{2: columnIDs, 1: {5}, 3: nil}))
+	if columnIDs[0] != 30 {
+		t.Fatalf("SetBitsFromMap should not sort the given column IDs")
+	}
+	comparePQL(t, "", sampleField.SetBitsFromMap(nil))
+}
+
+func TestSetBitIf(t *testing.T) {
+	comparePQL(t,
+		"SetBit(row=5, field='sample-field', col=10)",
+		sampleField.SetBitIf(true, 5, 10))
+	q := sampleField.SetBitIf(false, 5, 10)
+	comparePQL(t, "", q)
+	if q.Index() != sampleIndex {
+		t.Fatalf("The correct index should be assigned")
+	}
+	comparePQL(t,
+		"SetBit(row=1, field='sample-field', col=2)",
+		sampleIndex.BatchQuery(sampleField.SetBitIf(false, 5, 10), sampleField.SetBitIf(true, 1, 2)))
+}
+
+func TestSetBitK(t *testing.T) {
+	comparePQL(t,
+		"SetBit(row='myrow', field='sample-field', col='mycol')",
+		sampleField.SetBitK("myrow", "mycol"))
+}
+
+func TestSetBitTimestamp(t *testing.T) {
+	timestamp := time.Date(2017, time.April, 24, 12, 14, 0, 0, time.UTC)
 	comparePQL(t,
 		"SetBit(row=10, field='collaboration', col=20, timestamp='2017-04-24T12:14')",
 		collabField.SetBitTimestamp(10, 20, timestamp))
@@ -273,6 +1013,33 @@ func TestSetBitTimestampK(t *testing.T) {
 		collabField.SetBitTimestampK("myrow", "mycol", timestamp))
 }
 
+func TestIndexSetBit(t *testing.T) {
+	comparePQL(t,
+		"SetBit(row=5, field='sample-field', col=10)",
+		sampleIndex.SetBit("sample-field", 5, 10))
+	q := sampleIndex.SetBit("no-such-field", 5, 10)
+	if errors.Cause(q.Error()) != ErrFieldNotFound {
+		t.Fatalf("Expected ErrFieldNotFound, got %v", q.Error())
+	}
+	if _, ok := sampleIndex.Fields()["no-such-field"]; ok {
+		t.Fatalf("SetBit should not create the field")
+	}
+}
+
+func TestIndexRow(t *testing.T) {
+	q, err := sampleIndex.Row("sample-field", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t, "Bitmap(row=5, field='sample-field')", q)
+	if _, err := sampleIndex.Row("no-such-field", 5); errors.Cause(err) != ErrFieldNotFound {
+		t.Fatalf("Expected ErrFieldNotFound, got %v", err)
+	}
+	if _, ok := sampleIndex.Fields()["no-such-field"]; ok {
+		t.Fatalf("Row should not create the field")
+	}
+}
+
 func TestClearBit(t *testing.T) {
 	comparePQL(t,
 		"ClearBit(row=5, field='sample-field', col=10)",
@@ -285,6 +1052,33 @@ func TestClearBitK(t *testing.T) {
 		sampleField.ClearBitK("myrow", "mycol"))
 }
 
+func TestClearRow(t *testing.T) {
+	comparePQL(t,
+		"ClearRow(row=5, field='sample-field')",
+		sampleField.ClearRow(5))
+	comparePQL(t,
+		"ClearRow(row='it\\'s', field='sample-field')",
+		sampleField.ClearRowK("it's"))
+	q := sampleIndex.BatchQuery()
+	q.Add(sampleField.ClearRow(5))
+	q.Add(sampleField.SetRowAttrs(5, map[string]interface{}{"color?": 1}))
+	if q.Error() == nil {
+		t.Fatalf("batch should have the error of the failed query")
+	}
+}
+
+func TestIncludesColumn(t *testing.T) {
+	comparePQL(t,
+		"Count(Intersect(Bitmap(row=5, field='sample-field'), Bitmap(col=10)))",
+		sampleField.IncludesColumn(5, 10))
+}
+
+func TestIncludesColumnK(t *testing.T) {
+	comparePQL(t,
+		"Count(Intersect(Bitmap(row='myrow', field='sample-field'), Bitmap(col='mycol')))",
+		sampleField.IncludesColumnK("myrow", "mycol"))
+}
+
 func TestSetValue(t *testing.T) {
 	comparePQL(t,
 		"SetValue(col=50, collaboration=15)",
@@ -310,9 +1104,9 @@ func TestUnion(t *testing.T) {
 	comparePQL(t,
 		"Union(Bitmap(row=10, field='sample-field'))",
 		sampleIndex.Union(b1))
-	comparePQL(t,
-		"Union()",
-		sampleIndex.Union())
+	if sampleIndex.Union().Error() == nil {
+		t.Fatalf("Union without rows should fail")
+	}
 }
 
 func TestIntersect(t *testing.T) {
@@ -330,6 +1124,44 @@ func TestIntersect(t *testing.T) {
 		sampleIndex.Intersect(b1))
 }
 
+func TestRawRowQuery(t *testing.T) {
+	raw := sampleIndex.RawRowQuery("Bitmap(row=5, field='raw-field')")
+	if raw.serialize() != "Bitmap(row=5, field='raw-field')" {
+		t.Fatalf("raw query should not be modified: %s", raw.serialize())
+	}
+	comparePQL(t,
+		"Union(Bitmap(row=5, field='raw-field'), Bitmap(row=10, field='sample-field'))",
+		sampleIndex.Union(raw, b1))
+	comparePQL(t,
+		"Count(Bitmap(row=5, field='raw-field'))",
+		sampleIndex.Count(raw))
+}
+
+func TestUnionQIntersectQ(t *testing.T) {
+	queries := map[string]PQLQuery{"b1": b1, "b2": b2}
+	comparePQL(t,
+		"Union(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field'))",
+		sampleIndex.UnionQ(queries["b1"], queries["b2"]))
+	comparePQL(t,
+		"Intersect(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field'))",
+		sampleIndex.IntersectQ(queries["b1"], queries["b2"]))
+	if sampleIndex.UnionQ().Error() == nil {
+		t.Fatalf("UnionQ without queries should have failed")
+	}
+	invalid := []PQLQuery{sampleIndex.Count(b1), sampleIndex.BatchQuery(b1), Noop, nil}
+	for i, query := range invalid {
+		if sampleIndex.UnionQ(b1, query).Error() == nil {
+			t.Fatalf("%d: UnionQ should have failed", i)
+		}
+		if sampleIndex.IntersectQ(b1, query).Error() == nil {
+			t.Fatalf("%d: IntersectQ should have failed", i)
+		}
+	}
+	if sampleIndex.IntersectQ().Error() == nil {
+		t.Fatalf("IntersectQ without queries should have failed")
+	}
+}
+
 func TestDifference(t *testing.T) {
 	comparePQL(t,
 		"Difference(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field'))",
@@ -345,6 +1177,21 @@ func TestDifference(t *testing.T) {
 		sampleIndex.Difference(b1))
 }
 
+func TestDifferenceFrom(t *testing.T) {
+	comparePQL(t,
+		"Difference(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field'))",
+		sampleIndex.DifferenceFrom(b1, b2))
+	comparePQL(t,
+		"Difference(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field'), Bitmap(row=42, field='sample-field'))",
+		sampleIndex.DifferenceFrom(b1, b2, b3))
+	if sampleIndex.DifferenceFrom(nil, b2).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if sampleIndex.DifferenceFrom(b1).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
 func TestXor(t *testing.T) {
 	comparePQL(t,
 		"Xor(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field'))",
@@ -357,13 +1204,76 @@ func TestXor(t *testing.T) {
 		sampleIndex.Xor(b1, b4))
 }
 
+func TestNot(t *testing.T) {
+	comparePQL(t,
+		"Not(Bitmap(row=10, field='sample-field'))",
+		sampleIndex.Not(b1))
+	comparePQL(t,
+		"Not(Bitmap(row=1, field='sample-field'))",
+		sampleField.Row(1).Negate())
+	comparePQL(t,
+		"Not(Union(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field')))",
+		sampleIndex.Union(b1, b2).Negate())
+	if sampleIndex.Not(nil).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	var row *PQLRowQuery
+	if row.Negate().Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestRowCount(t *testing.T) {
+	comparePQL(t,
+		"Count(Bitmap(row=10, field='sample-field'))",
+		b1.Count())
+	comparePQL(t,
+		"Count(Intersect(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field')))",
+		sampleIndex.Intersect(b1, b2).Count())
+}
+
+func TestRowTopN(t *testing.T) {
+	comparePQL(t,
+		"TopN(Intersect(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field')), field='sample-field', n=10)",
+		sampleIndex.Intersect(b1, b2).TopN(sampleField, 10))
+	if b1.TopN(collabField, 10).Error() == nil {
+		t.Fatalf("field of another index should fail")
+	}
+	if b1.TopN(nil, 10).Error() == nil {
+		t.Fatalf("nil field should fail")
+	}
+}
+
+func TestRowSum(t *testing.T) {
+	comparePQL(t,
+		"Sum(Bitmap(row=2, field='collaboration'), field='collaboration')",
+		b4.Sum(collabField))
+	if b1.Sum(collabField).Error() == nil {
+		t.Fatalf("field of another index should fail")
+	}
+	if b1.Sum(nil).Error() == nil {
+		t.Fatalf("nil field should fail")
+	}
+	copiedField, _ := projectIndex.Copy().FieldByName(collabField.Name())
+	comparePQL(t,
+		"Sum(Bitmap(row=2, field='collaboration'), field='collaboration')",
+		b4.Sum(copiedField))
+}
+
+func TestAll(t *testing.T) {
+	comparePQL(t, "All()", sampleIndex.All())
+	comparePQL(t,
+		"Difference(All(), Bitmap(row=10, field='sample-field'))",
+		sampleIndex.Difference(sampleIndex.All(), b1))
+}
+
 func TestTopN(t *testing.T) {
 	comparePQL(t,
 		"TopN(field='sample-field', n=27)",
 		sampleField.TopN(27))
 	comparePQL(t,
-		"TopN(Bitmap(row=3, field='collaboration'), field='sample-field', n=10)",
-		sampleField.RowTopN(10, collabField.Row(3)))
+		"TopN(Bitmap(row=3, field='sample-field'), field='sample-field', n=10)",
+		sampleField.RowTopN(10, sampleField.Row(3)))
 	comparePQL(t,
 		"TopN(Bitmap(row=7, field='collaboration'), field='sample-field', n=12, field='category', filters=[80,81])",
 		sampleField.FilterFieldTopN(12, collabField.Row(7), "category", 80, 81))
@@ -372,6 +1282,187 @@ func TestTopN(t *testing.T) {
 		sampleField.FilterFieldTopN(12, nil, "category", 80, 81))
 }
 
+func TestFilterFieldTopNK(t *testing.T) {
+	comparePQL(t,
+		"TopN(Bitmap(row='foo\\'s', field='sample-field'), field='sample-field', n=12, field='category', filters=[\"a\",\"b\"])",
+		sampleField.FilterFieldTopNK(12, "foo's", "category", "a", "b"))
+	if sampleField.FilterFieldTopNK(12, "foo", "$invalid$", 80).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestTopNPaged(t *testing.T) {
+	comparePQL(t,
+		"TopN(field='sample-field', n=10)",
+		sampleField.TopNPaged(10, 0))
+	comparePQL(t,
+		"TopN(field='sample-field', n=20)",
+		sampleField.TopNPaged(10, 1))
+	if sampleField.TopNPaged(0, 1).Error() == nil {
+		t.Fatalf("page size 0 should fail")
+	}
+	if sampleField.TopNPaged(math.MaxUint64/2, 2).Error() == nil {
+		t.Fatalf("overflowing page should fail")
+	}
+}
+
+func TestTopNFiltered(t *testing.T) {
+	comparePQL(t,
+		"TopN(field='sample-field', n=27)",
+		sampleField.TopNFiltered(27, nil))
+	comparePQL(t,
+		"TopN(Bitmap(row=3, field='sample-field'), field='sample-field', n=10)",
+		sampleField.TopNFiltered(10, sampleField.Row(3)))
+}
+
+func TestRowTopNIndexMismatch(t *testing.T) {
+	for _, q := range []*PQLRowQuery{
+		sampleField.RowTopN(10, collabField.Row(3)),
+		sampleField.TopNFiltered(10, collabField.Row(3)),
+	} {
+		if q.Error() == nil || errors.Cause(q.Error()).Error() != "Error: row index mismatch in RowTopN" {
+			t.Fatalf("Expected index mismatch error, got %v", q.Error())
+		}
+	}
+
+	copied := sampleIndex.Copy()
+	copiedField, ok := copied.FieldByName(sampleField.Name())
+	if !ok {
+		t.Fatalf("copied index should have the field")
+	}
+	comparePQL(t,
+		"TopN(Bitmap(row=10, field='sample-field'), field='sample-field', n=10)",
+		copiedField.TopNFiltered(10, b1))
+	comparePQL(t,
+		"TopN(Bitmap(row=3, field='sample-field'), field='sample-field', n=10)",
+		sampleField.TopNFiltered(10, copiedField.Row(3)))
+}
+
+func TestTopNCross(t *testing.T) {
+	field, err := projectIndex.Field("topn-cross-field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := projectIndex.TopNCross(5, []*Field{collabField, field})
+	if q.Error() != nil {
+		t.Fatal(q.Error())
+	}
+	comparePQL(t,
+		"TopN(field='collaboration', n=5)TopN(field='topn-cross-field', n=5)",
+		q)
+	if projectIndex.TopNCross(5, []*Field{collabField, sampleField}).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if projectIndex.TopNCross(5, []*Field{nil}).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	copiedField, _ := projectIndex.Copy().FieldByName(collabField.Name())
+	comparePQL(t,
+		"TopN(field='collaboration', n=5)",
+		projectIndex.TopNCross(5, []*Field{copiedField}))
+}
+
+func TestCountAll(t *testing.T) {
+	field, err := projectIndex.Field("count-all-field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := projectIndex.CountAll([]*Field{collabField, field}, b4)
+	if q.Error() != nil {
+		t.Fatal(q.Error())
+	}
+	comparePQL(t,
+		"TopN(Bitmap(row=2, field='collaboration'), field='collaboration', n=0)TopN(Bitmap(row=2, field='collaboration'), field='count-all-field', n=0)",
+		q)
+	comparePQL(t,
+		"TopN(field='count-all-field', n=0)",
+		projectIndex.CountAll([]*Field{field}, nil))
+	if projectIndex.CountAll([]*Field{sampleField}, b4).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if projectIndex.CountAll([]*Field{collabField, nil}, b4).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	copiedField, _ := projectIndex.Copy().FieldByName(collabField.Name())
+	comparePQL(t,
+		"TopN(Bitmap(row=2, field='collaboration'), field='collaboration', n=0)",
+		projectIndex.CountAll([]*Field{copiedField}, b4))
+}
+
+func TestBatchQueryForRowIDs(t *testing.T) {
+	count := func(rowID uint64) PQLQuery {
+		return sampleField.Row(rowID).Count()
+	}
+	comparePQL(t,
+		"Count(Bitmap(row=1, field='sample-field'))Count(Bitmap(row=2, field='sample-field'))",
+		sampleIndex.BatchQueryForRowIDs(sampleField, []uint64{1, 2}, count))
+	comparePQL(t,
+		"",
+		sampleIndex.BatchQueryForRowIDs(sampleField, nil, count))
+	if sampleIndex.BatchQueryForRowIDs(collabField, []uint64{1}, count).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if sampleIndex.BatchQueryForRowIDs(nil, []uint64{1}, count).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	copiedField, _ := sampleIndex.Copy().FieldByName(sampleField.Name())
+	comparePQL(t,
+		"Count(Bitmap(row=1, field='sample-field'))",
+		sampleIndex.BatchQueryForRowIDs(copiedField, []uint64{1}, count))
+	failing := func(rowID uint64) PQLQuery {
+		return sampleField.RowTopN(5, b4)
+	}
+	if sampleIndex.BatchQueryForRowIDs(sampleField, []uint64{1}, failing).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestBatchQueryForRowKeys(t *testing.T) {
+	count := func(rowKey string) PQLQuery {
+		return sampleField.RowK(rowKey).Count()
+	}
+	comparePQL(t,
+		"Count(Bitmap(row='foo', field='sample-field'))Count(Bitmap(row='it\\'s', field='sample-field'))",
+		sampleIndex.BatchQueryForRowKeys(sampleField, []string{"foo", "it's"}, count))
+	q := sampleIndex.BatchQueryForRowKeys(sampleField, []string{"foo", ""}, count)
+	if errors.Cause(q.Error()) != ErrInvalidKey {
+		t.Fatalf("Expected ErrInvalidKey, got %v", q.Error())
+	}
+	if q.Count() != 0 {
+		t.Fatalf("No query should be created when a key is invalid")
+	}
+	if sampleIndex.BatchQueryForRowKeys(collabField, []string{"foo"}, count).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	copiedField, _ := sampleIndex.Copy().FieldByName(sampleField.Name())
+	comparePQL(t,
+		"Count(Bitmap(row='foo', field='sample-field'))",
+		sampleIndex.BatchQueryForRowKeys(copiedField, []string{"foo"}, count))
+}
+
+func TestRowTopNK(t *testing.T) {
+	field, err := projectIndex.Field("topnk-field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t,
+		"TopN(Bitmap(row='foo', field='collaboration'), field='topnk-field', n=10)",
+		field.RowTopNK(10, "foo", "collaboration"))
+	if err := field.RowTopNK(10, "foo", "no-such-field").Error(); errors.Cause(err) != ErrFieldNotFound {
+		t.Fatalf("Expected ErrFieldNotFound, got %v", err)
+	}
+}
+
+func TestTopNThreshold(t *testing.T) {
+	// treshold is the spelling of the argument the Pilosa server parses.
+	comparePQL(t,
+		"TopN(field='sample-field', n=27, treshold=5)",
+		sampleField.TopNThreshold(27, 5))
+	if sampleField.TopNThreshold(27, 0).serialize() != sampleField.TopN(27).serialize() {
+		t.Fatalf("TopNThreshold with threshold 0 should be the same as TopN")
+	}
+}
+
 func TestFieldLT(t *testing.T) {
 	comparePQL(t,
 		"Range(collaboration < 10)",
@@ -420,6 +1511,56 @@ func TestFieldBetween(t *testing.T) {
 		collabField.Between(10, 20))
 }
 
+func TestFieldComparisonLargeValues(t *testing.T) {
+	comparePQL(t,
+		"Range(collaboration < 9223372036854775807)",
+		collabField.LT(math.MaxInt64))
+	comparePQL(t,
+		"Range(collaboration >= -9223372036854775808)",
+		collabField.GTE(math.MinInt64))
+	comparePQL(t,
+		"Range(collaboration >< [-4294967296,4294967296])",
+		collabField.Between(-1<<32, 1<<32))
+}
+
+func TestFieldComparisonInt(t *testing.T) {
+	comparePQL(t, "Range(collaboration < 10)", collabField.LTInt(10))
+	comparePQL(t, "Range(collaboration <= 10)", collabField.LTEInt(10))
+	comparePQL(t, "Range(collaboration > 10)", collabField.GTInt(10))
+	comparePQL(t, "Range(collaboration >= 10)", collabField.GTEInt(10))
+	comparePQL(t, "Range(collaboration == 10)", collabField.EqualsInt(10))
+	comparePQL(t, "Range(collaboration != 10)", collabField.NotEqualsInt(10))
+	comparePQL(t, "Range(collaboration >< [10,20])", collabField.BetweenInt(10, 20))
+}
+
+func TestFieldBetweenExclusive(t *testing.T) {
+	comparePQL(t,
+		"Intersect(Range(collaboration > 10), Range(collaboration < 20))",
+		collabField.BetweenExclusive(10, 20))
+	// there is no value greater than and less than 10
+	comparePQL(t,
+		"Intersect(Range(collaboration > 10), Range(collaboration < 10))",
+		collabField.BetweenExclusive(10, 10))
+}
+
+func TestFieldBetweenLeftOpen(t *testing.T) {
+	comparePQL(t,
+		"Intersect(Range(collaboration > 10), Range(collaboration <= 20))",
+		collabField.BetweenLeftOpen(10, 20))
+	comparePQL(t,
+		"Intersect(Range(collaboration > 10), Range(collaboration <= 10))",
+		collabField.BetweenLeftOpen(10, 10))
+}
+
+func TestFieldBetweenRightOpen(t *testing.T) {
+	comparePQL(t,
+		"Intersect(Range(collaboration >= 10), Range(collaboration < 20))",
+		collabField.BetweenRightOpen(10, 20))
+	comparePQL(t,
+		"Intersect(Range(collaboration >= 10), Range(collaboration < 10))",
+		collabField.BetweenRightOpen(10, 10))
+}
+
 func TestFieldSum(t *testing.T) {
 	comparePQL(t,
 		"Sum(Bitmap(row=10, field='collaboration'), field='collaboration')",
@@ -429,18 +1570,86 @@ func TestFieldSum(t *testing.T) {
 		collabField.Sum(nil))
 }
 
-func TestFieldBSetIntValue(t *testing.T) {
+func TestFieldAggregatesWithoutRow(t *testing.T) {
 	comparePQL(t,
-		"SetValue(col=10, collaboration=20)",
-		collabField.SetIntValue(10, 20))
+		"Sum(field='collaboration')",
+		collabField.Sum(nil))
+	comparePQL(t,
+		"Min(field='collaboration')",
+		collabField.Min(nil))
+	comparePQL(t,
+		"Max(field='collaboration')",
+		collabField.Max(nil))
 }
 
-func TestFilterFieldTopNInvalidField(t *testing.T) {
-	q := sampleField.FilterFieldTopN(12, collabField.Row(7), "$invalid$", 80, 81)
-	if q.Error() == nil {
-		t.Fatalf("should have failed")
-	}
-}
+func TestValQuery(t *testing.T) {
+	comparePQL(t,
+		"Avg(field='collaboration')",
+		collabField.ValQuery("Avg", nil))
+	comparePQL(t,
+		"Avg(Bitmap(row=2, field='collaboration'), field='collaboration')",
+		collabField.ValQuery("Avg", b4))
+	comparePQL(t,
+		"Sum(field='collaboration')",
+		collabField.ValQuery("Sum", nil))
+	for _, op := range []string{"", "1Avg", "Avg(", "Avg x", "Avg)Bitmap(row=1"} {
+		if collabField.ValQuery(op, nil).Error() == nil {
+			t.Fatalf("%q: should have failed", op)
+		}
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	comparePQL(t,
+		"Distinct(field='collaboration')",
+		collabField.Distinct())
+	comparePQL(t,
+		"Distinct(Bitmap(row=10, field='sample-field'), field='collaboration')",
+		collabField.DistinctFiltered(b1))
+	comparePQL(t,
+		"Distinct(field='collaboration')",
+		collabField.DistinctFiltered(nil))
+	comparePQL(t,
+		"Count(Distinct(field='collaboration'))",
+		projectIndex.CountDistinct(collabField))
+	if projectIndex.CountDistinct(nil).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestSetIntValueLargeValues(t *testing.T) {
+	comparePQL(t,
+		"SetValue(col=10, collaboration=9223372036854775807)",
+		collabField.SetIntValue(10, math.MaxInt64))
+	comparePQL(t,
+		"SetValue(col=10, collaboration=-9223372036854775807)",
+		collabField.SetIntValue(10, -math.MaxInt64))
+	comparePQL(t,
+		"SetValue(col='mycol', collaboration=9223372036854775806)",
+		collabField.SetIntValueK("mycol", math.MaxInt64-1))
+}
+
+func TestSetIntValueInt(t *testing.T) {
+	comparePQL(t,
+		"SetValue(col=10, collaboration=20)",
+		collabField.SetIntValueInt(10, 20))
+	comparePQL(t,
+		"SetValue(col='mycol', collaboration=20)",
+		collabField.SetIntValueKInt("mycol", 20))
+}
+
+func TestFieldBSetIntValue(t *testing.T) {
+	comparePQL(t,
+		"SetValue(col=10, collaboration=20)",
+		collabField.SetIntValue(10, 20))
+}
+
+func TestFilterFieldTopNInvalidField(t *testing.T) {
+	q := sampleField.FilterFieldTopN(12, collabField.Row(7), "$invalid$", 80, 81)
+	if q.Error() == nil {
+		t.Fatalf("should have failed")
+	}
+}
 
 func TestFilterFieldTopNInvalidValue(t *testing.T) {
 	q := sampleField.FilterFieldTopN(12, collabField.Row(7), "category", 80, func() {})
@@ -504,6 +1713,191 @@ func TestSetColumnAttrsInvalidAttr(t *testing.T) {
 	}
 }
 
+func TestNewColumnAttrPair(t *testing.T) {
+	pair, err := NewColumnAttrPair(5, map[string]interface{}{"happy": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.ColumnID != 5 || pair.Attrs["happy"] != true {
+		t.Fatalf("pair was not set correctly: %v", pair)
+	}
+	_, err = NewColumnAttrPair(5, map[string]interface{}{"$invalid$": true})
+	if err == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestSetColumnAttrsBatch(t *testing.T) {
+	pairs := []ColumnAttrPair{
+		{ColumnID: 5, Attrs: map[string]interface{}{"happy": true}},
+		{ColumnID: 3, Attrs: map[string]interface{}{"color": "blue"}},
+		{ColumnID: 5, Attrs: map[string]interface{}{"happy": false}},
+	}
+	q, errs := projectIndex.SetColumnAttrsBatch(pairs)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	comparePQL(t,
+		"SetColumnAttrs(col=5, happy=true)SetColumnAttrs(col=3, color=\"blue\")SetColumnAttrs(col=5, happy=false)",
+		q)
+	pairs = append(pairs,
+		ColumnAttrPair{ColumnID: 6, Attrs: map[string]interface{}{"$invalid$": 1}},
+		ColumnAttrPair{ColumnID: 7, Attrs: map[string]interface{}{"size": 2}},
+		ColumnAttrPair{ColumnID: 8, Attrs: map[string]interface{}{"color?": 1}},
+	)
+	q, errs = projectIndex.SetColumnAttrsBatch(pairs)
+	if len(errs) != 2 || errs[6] == nil || errs[8] == nil {
+		t.Fatalf("columns 6 and 8 should have errors: %v", errs)
+	}
+	if errors.Cause(errs[6]) != ErrInvalidLabel {
+		t.Fatalf("expected ErrInvalidLabel, got %v", errs[6])
+	}
+	if q.Error() != nil {
+		t.Fatalf("batch should not have an error: %v", q.Error())
+	}
+	comparePQL(t,
+		"SetColumnAttrs(col=5, happy=true)SetColumnAttrs(col=3, color=\"blue\")SetColumnAttrs(col=5, happy=false)SetColumnAttrs(col=7, size=2)",
+		q)
+}
+
+func TestSetColumnAttrsK(t *testing.T) {
+	comparePQL(t,
+		"SetColumnAttrs(col='it\\'s', happy=true)",
+		projectIndex.SetColumnAttrsK("it's", map[string]interface{}{"happy": true}))
+	if projectIndex.SetColumnAttrsK("foo", map[string]interface{}{"$invalid$": 1}).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestSetColumnAttrsMany(t *testing.T) {
+	q := projectIndex.SetColumnAttrsMany(map[uint64]map[string]interface{}{
+		5:  {"happy": true},
+		3:  {"quote": "\"Don't worry\""},
+		10: {"color": "blue", "size": 2},
+	})
+	if q.Error() != nil {
+		t.Fatal(q.Error())
+	}
+	if q.Count() != 3 {
+		t.Fatalf("batch should have 3 queries, got %d", q.Count())
+	}
+	comparePQL(t,
+		"SetColumnAttrs(col=3, quote=\"\\\"Don't worry\\\"\")SetColumnAttrs(col=5, happy=true)SetColumnAttrs(col=10, color=\"blue\", size=2)",
+		q)
+	if projectIndex.SetColumnAttrsMany(map[uint64]map[string]interface{}{5: {"$invalid$": 1}}).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if projectIndex.SetColumnAttrsMany(nil).Count() != 0 {
+		t.Fatalf("batch should be empty")
+	}
+}
+
+func TestSetColumnAttrsManyK(t *testing.T) {
+	q := projectIndex.SetColumnAttrsManyK(map[string]map[string]interface{}{
+		"b":    {"happy": true},
+		"a'b":  {"color": "blue"},
+		"quux": {"size": 2},
+	})
+	if q.Error() != nil {
+		t.Fatal(q.Error())
+	}
+	if q.Count() != 3 {
+		t.Fatalf("batch should have 3 queries, got %d", q.Count())
+	}
+	comparePQL(t,
+		"SetColumnAttrs(col='a\\'b', color=\"blue\")SetColumnAttrs(col='b', happy=true)SetColumnAttrs(col='quux', size=2)",
+		q)
+	if projectIndex.SetColumnAttrsManyK(map[string]map[string]interface{}{"a": {"$invalid$": 1}}).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestSetBitMatrix(t *testing.T) {
+	matrix := [][]uint64{
+		{0, 1},
+		{},
+		{1, 0, 5},
+	}
+	q := sampleIndex.SetBitMatrix(sampleField, matrix)
+	if q.Error() != nil {
+		t.Fatal(q.Error())
+	}
+	comparePQL(t,
+		"SetBit(row=0, field='sample-field', col=1)SetBit(row=2, field='sample-field', col=0)SetBit(row=2, field='sample-field', col=2)",
+		q)
+	if cap(q.queries) != 3 {
+		t.Fatalf("batch should be preallocated for 3 queries, got %d", cap(q.queries))
+	}
+	comparePQL(t, "", sampleIndex.SetBitMatrix(sampleField, nil))
+	if projectIndex.SetBitMatrix(sampleField, matrix).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if sampleIndex.SetBitMatrix(nil, matrix).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	copiedField, _ := sampleIndex.Copy().FieldByName(sampleField.Name())
+	comparePQL(t,
+		"SetBit(row=0, field='sample-field', col=1)SetBit(row=2, field='sample-field', col=0)SetBit(row=2, field='sample-field', col=2)",
+		sampleIndex.SetBitMatrix(copiedField, matrix))
+}
+
+func TestSetBitsCSV(t *testing.T) {
+	q, err := sampleIndex.SetBitsCSV(sampleField, 5, "1,2, 3 ,4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t,
+		"SetBit(row=5, field='sample-field', col=1)SetBit(row=5, field='sample-field', col=2)SetBit(row=5, field='sample-field', col=3)SetBit(row=5, field='sample-field', col=4)",
+		q)
+	q, err = sampleIndex.SetBitsCSV(sampleField, 5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t, "", q)
+	for _, csv := range []string{"1,x", "1,,2", "-1", "1.5"} {
+		if _, err := sampleIndex.SetBitsCSV(sampleField, 5, csv); err == nil {
+			t.Fatalf("%s: should have failed", csv)
+		}
+	}
+	if _, err := sampleIndex.SetBitsCSV(collabField, 5, "1"); err == nil {
+		t.Fatalf("field of another index should fail")
+	}
+	if _, err := sampleIndex.SetBitsCSV(nil, 5, "1"); err == nil {
+		t.Fatalf("nil field should fail")
+	}
+	copiedField, _ := sampleIndex.Copy().FieldByName(sampleField.Name())
+	q, err = sampleIndex.SetBitsCSV(copiedField, 5, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t, "SetBit(row=5, field='sample-field', col=1)", q)
+}
+
+func TestClearBitMatrix(t *testing.T) {
+	matrix := [][]uint64{
+		{1},
+		{0, 0, 1},
+	}
+	q := sampleIndex.ClearBitMatrix(sampleField, matrix)
+	if q.Error() != nil {
+		t.Fatal(q.Error())
+	}
+	comparePQL(t,
+		"ClearBit(row=0, field='sample-field', col=0)ClearBit(row=1, field='sample-field', col=2)",
+		q)
+	if projectIndex.ClearBitMatrix(sampleField, matrix).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+
+	batch := sampleIndex.BatchQuery(
+		sampleIndex.SetBitMatrix(sampleField, [][]uint64{{0, 1}}),
+		sampleIndex.ClearBitMatrix(sampleField, [][]uint64{{1}}),
+	)
+	comparePQL(t,
+		"SetBit(row=0, field='sample-field', col=1)ClearBit(row=0, field='sample-field', col=0)",
+		batch)
+}
+
 func TestSetRowAttrsTest(t *testing.T) {
 	attrs := map[string]interface{}{
 		"quote":  "\"Don't worry, be happy\"",
@@ -567,6 +1961,332 @@ func TestBatchQueryWithError(t *testing.T) {
 	}
 }
 
+func TestBatchQueryMerge(t *testing.T) {
+	q := sampleIndex.BatchQuery(b1)
+	other := sampleIndex.BatchQuery(b2, b3)
+	if err := q.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t,
+		"Bitmap(row=10, field='sample-field')Bitmap(row=20, field='sample-field')Bitmap(row=42, field='sample-field')",
+		q)
+	if other.Count() != 2 {
+		t.Fatalf("Merge should not modify the other batch")
+	}
+	if err := q.Merge(nil); err != nil || q.Count() != 3 {
+		t.Fatalf("merging nil should do nothing")
+	}
+
+	failed := sampleIndex.BatchQuery()
+	failed.Add(sampleIndex.Xor(b1))
+	if err := q.Merge(failed); err != nil {
+		t.Fatal(err)
+	}
+	if q.Error() == nil {
+		t.Fatalf("error of the other batch should be kept")
+	}
+
+	q = sampleIndex.BatchQuery(b1)
+	if err := q.Merge(sampleIndex.Copy().BatchQuery(b2)); err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t,
+		"Bitmap(row=10, field='sample-field')Bitmap(row=20, field='sample-field')",
+		q)
+
+	q = sampleIndex.BatchQuery(b1)
+	if err := q.Merge(projectIndex.BatchQuery(b4)); err != ErrIndexMismatch {
+		t.Fatalf("expected ErrIndexMismatch, got %v", err)
+	}
+	if q.Count() != 1 {
+		t.Fatalf("batch should not change on index mismatch")
+	}
+}
+
+func TestBatchQueryQueries(t *testing.T) {
+	q := sampleIndex.BatchQuery(sampleField.Row(1), sampleField.SetBit(1, 2))
+	target := []string{
+		"Bitmap(row=1, field='sample-field')",
+		"SetBit(row=1, field='sample-field', col=2)",
+	}
+	queries := q.Queries()
+	if !reflect.DeepEqual(target, queries) {
+		t.Fatalf("%v != %v", target, queries)
+	}
+	if q.Count() != 2 {
+		t.Fatalf("Count should be 2, got %d", q.Count())
+	}
+	queries[0] = "changed"
+	if q.Queries()[0] != target[0] {
+		t.Fatalf("The batch should not be modified")
+	}
+	if sampleIndex.BatchQuery().Count() != 0 {
+		t.Fatalf("Count should be 0")
+	}
+}
+
+func TestQueryPQLIsFixedOnCreation(t *testing.T) {
+	attrs := map[string]interface{}{"active": true}
+	rowAttrs := sampleField.SetRowAttrs(5, attrs)
+	attrs["active"] = false
+	attrs["extra"] = 1
+	comparePQL(t, "SetRowAttrs(row=5, field='sample-field', active=true)", rowAttrs)
+
+	matrix := [][]uint64{{1}}
+	setMatrix := sampleIndex.SetBitMatrix(sampleField, matrix)
+	matrix[0][0] = 0
+	comparePQL(t, "SetBit(row=0, field='sample-field', col=0)", setMatrix)
+
+	batch := sampleIndex.BatchQuery(b1)
+	queries := batch.Queries()
+	queries[0] = "Modified()"
+	union := sampleIndex.Union(b1, b2)
+	batch.Add(b2)
+	comparePQL(t, "Bitmap(row=10, field='sample-field')Bitmap(row=20, field='sample-field')", batch)
+	comparePQL(t, "Union(Bitmap(row=10, field='sample-field'), Bitmap(row=20, field='sample-field'))", union)
+}
+
+func TestQueryByteSize(t *testing.T) {
+	row := sampleField.Row(1)
+	if row.ByteSize() != len(row.serialize()) {
+		t.Fatalf("%d != %d", len(row.serialize()), row.ByteSize())
+	}
+	base := sampleField.SetBitK("it's", "foo")
+	if base.ByteSize() != len(base.serialize()) {
+		t.Fatalf("%d != %d", len(base.serialize()), base.ByteSize())
+	}
+	q := sampleIndex.BatchQuery(row, base, Noop)
+	if q.EstimatedByteSize() != len(q.serialize()) {
+		t.Fatalf("%d != %d", len(q.serialize()), q.EstimatedByteSize())
+	}
+	if sampleIndex.BatchQuery().EstimatedByteSize() != 0 {
+		t.Fatalf("Empty batch should have size 0")
+	}
+}
+
+func TestBatchQueryReset(t *testing.T) {
+	q := sampleIndex.BatchQuery(sampleField.Row(1))
+	q.Add(sampleField.FilterFieldTopN(12, collabField.Row(7), "$invalid$", 80, 81))
+	if q.Error() == nil {
+		t.Fatalf("The error must be set")
+	}
+	queries := q.Queries()
+	q.Reset()
+	if q.Error() != nil {
+		t.Fatalf("Error should be nil")
+	}
+	if q.Count() != 0 {
+		t.Fatalf("Batch should be empty")
+	}
+	if q.Index() != sampleIndex {
+		t.Fatalf("The index should not change")
+	}
+	q.Add(sampleField.Row(2))
+	comparePQL(t, "Bitmap(row=2, field='sample-field')", q)
+	if queries[0] != "Bitmap(row=1, field='sample-field')" {
+		t.Fatalf("Queries returned before Reset should not change")
+	}
+}
+
+func TestBatchQueryDeduplicate(t *testing.T) {
+	q := sampleIndex.BatchQuery(
+		sampleField.SetBit(1, 2),
+		sampleField.SetBit(3, 4),
+		sampleField.SetBit(1, 2),
+		sampleField.Row(1),
+		sampleField.SetBit(3, 4),
+	)
+	d := q.Deduplicate()
+	if d.Index() != sampleIndex {
+		t.Fatalf("The correct index should be assigned")
+	}
+	comparePQL(t,
+		"SetBit(row=1, field='sample-field', col=2)SetBit(row=3, field='sample-field', col=4)Bitmap(row=1, field='sample-field')",
+		d)
+	if len(q.queries) != 5 {
+		t.Fatalf("The original batch should not be modified")
+	}
+}
+
+func TestBatchQueryCompact(t *testing.T) {
+	q := sampleIndex.BatchQuery(
+		Noop,
+		sampleField.SetBitIf(true, 1, 2),
+		sampleField.SetBitIf(false, 3, 4),
+		Noop,
+	)
+	if q.Count() != 4 {
+		t.Fatalf("Batch should have 4 queries, got %d", q.Count())
+	}
+	c := q.Compact()
+	if c.Count() != 1 {
+		t.Fatalf("Compacted batch should have 1 query, got %d", c.Count())
+	}
+	comparePQL(t, "SetBit(row=1, field='sample-field', col=2)", c)
+
+	q.Add(sampleField.FilterFieldTopN(12, collabField.Row(7), "$invalid$", 80, 81))
+	if q.Compact().Error() == nil {
+		t.Fatalf("The error must be kept")
+	}
+}
+
+func TestBatchQueryReverse(t *testing.T) {
+	q := sampleIndex.BatchQuery(
+		sampleField.SetBit(1, 2),
+		sampleField.ClearBit(1, 2),
+		sampleField.Row(1),
+	)
+	comparePQL(t,
+		"Bitmap(row=1, field='sample-field')ClearBit(row=1, field='sample-field', col=2)SetBit(row=1, field='sample-field', col=2)",
+		q.Reverse())
+	comparePQL(t, "", sampleIndex.BatchQuery().Reverse())
+	if !strings.HasPrefix(q.serialize(), "SetBit") {
+		t.Fatalf("The original batch should not be modified")
+	}
+}
+
+func TestBatchQueryTakeSkip(t *testing.T) {
+	q := sampleIndex.BatchQuery(
+		sampleField.Row(1),
+		sampleField.Row(2),
+		sampleField.Row(3),
+	)
+	comparePQL(t, "Bitmap(row=1, field='sample-field')Bitmap(row=2, field='sample-field')", q.Take(2))
+	comparePQL(t, "Bitmap(row=3, field='sample-field')", q.Skip(2))
+	comparePQL(t, "Bitmap(row=2, field='sample-field')", q.Skip(1).Take(1))
+	comparePQL(t, q.serialize(), q.Take(10))
+	comparePQL(t, "", q.Take(0))
+	comparePQL(t, "", q.Take(-1))
+	comparePQL(t, q.serialize(), q.Skip(-1))
+	comparePQL(t, "", q.Skip(10))
+
+	taken := q.Take(1)
+	taken.Add(sampleField.Row(4))
+	comparePQL(t, "Bitmap(row=2, field='sample-field')", q.Skip(1).Take(1))
+}
+
+func BenchmarkBatchQuerySerialize(b *testing.B) {
+	q := sampleIndex.BatchQuery()
+	for i := 0; i < 10000; i++ {
+		q.Add(sampleField.SetBit(uint64(i), uint64(100000+i)))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.serialize()
+	}
+}
+
+func BenchmarkBatchQueryDeduplicate(b *testing.B) {
+	q := sampleIndex.BatchQuery()
+	for i := 0; i < 10000; i++ {
+		q.Add(sampleField.SetBit(uint64(i%5000), 1))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Deduplicate()
+	}
+}
+
+func TestQueryString(t *testing.T) {
+	batch := sampleIndex.BatchQuery(sampleField.Row(1), sampleField.SetBit(1, 2))
+	queries := []fmt.Stringer{
+		sampleField.Row(1),
+		sampleField.SetBit(1, 2),
+		batch,
+	}
+	targets := []string{
+		"Bitmap(row=1, field='sample-field')",
+		"SetBit(row=1, field='sample-field', col=2)",
+		"Bitmap(row=1, field='sample-field')SetBit(row=1, field='sample-field', col=2)",
+	}
+	for i, q := range queries {
+		if s := fmt.Sprintf("%s", q); s != targets[i] {
+			t.Fatalf("%s != %s", targets[i], s)
+		}
+	}
+}
+
+func TestQueryType(t *testing.T) {
+	queries := []PQLQuery{
+		sampleField.Row(1),
+		sampleField.SetBit(1, 2),
+		sampleIndex.BatchQuery(sampleField.Row(1)),
+	}
+	targets := []PQLQueryType{
+		QueryTypeRow,
+		QueryTypeBase,
+		QueryTypeBatch,
+	}
+	for i, q := range queries {
+		if q.QueryType() != targets[i] {
+			t.Fatalf("%s != %s", targets[i], q.QueryType())
+		}
+	}
+}
+
+func TestNoop(t *testing.T) {
+	comparePQL(t, "", Noop)
+	batch := sampleIndex.BatchQuery(b1, Noop)
+	comparePQL(t, "Bitmap(row=10, field='sample-field')", batch)
+	if batch.Count() != 2 || batch.Compact().Count() != 1 {
+		t.Fatalf("Noop should be kept in the batch until it is compacted")
+	}
+	if Noop.Index() != nil {
+		t.Fatalf("Noop should not have an index")
+	}
+	if Noop.Error() != nil {
+		t.Fatalf("Error should be nil")
+	}
+	if Noop.QueryType() != QueryTypeNoop {
+		t.Fatalf("%s != %s", QueryTypeNoop, Noop.QueryType())
+	}
+	q := sampleIndex.BatchQuery(sampleField.Row(1), Noop)
+	q.Add(Noop)
+	comparePQL(t, "Bitmap(row=1, field='sample-field')", q)
+
+	b := NewMultiIndexBatch()
+	b.Add(Noop)
+	if len(b.Queries()) != 0 {
+		t.Fatalf("Noop should not be added to a multi index batch")
+	}
+}
+
+func TestMultiIndexBatch(t *testing.T) {
+	b := NewMultiIndexBatch()
+	b.Add(sampleField.Row(1))
+	b.Add(collabField.Row(2))
+	b.Add(sampleField.SetBit(3, 4))
+	if b.Error() != nil {
+		t.Fatalf("Error should be nil")
+	}
+	queries := b.Queries()
+	if len(queries) != 2 {
+		t.Fatalf("there should be 2 batches, got %d", len(queries))
+	}
+	comparePQL(t,
+		"Bitmap(row=1, field='sample-field')SetBit(row=3, field='sample-field', col=4)",
+		queries["sample-index"])
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := `[{"index":"project-index","query":"Bitmap(row=2, field='collaboration')"},` +
+		`{"index":"sample-index","query":"Bitmap(row=1, field='sample-field')SetBit(row=3, field='sample-field', col=4)"}]`
+	if target != string(data) {
+		t.Fatalf("%s != %s", target, string(data))
+	}
+}
+
+func TestMultiIndexBatchWithError(t *testing.T) {
+	b := NewMultiIndexBatch()
+	b.Add(sampleField.Row(1))
+	b.Add(sampleField.FilterFieldTopN(12, collabField.Row(7), "$invalid$", 80, 81))
+	if b.Error() == nil {
+		t.Fatalf("The error must be set")
+	}
+}
+
 func TestCount(t *testing.T) {
 	q := projectIndex.Count(collabField.Row(42))
 	comparePQL(t, "Count(Bitmap(row=42, field='collaboration'))", q)
@@ -588,6 +2308,139 @@ func TestRangeK(t *testing.T) {
 		collabField.RangeK("foo", start, end))
 }
 
+func TestRangeLast(t *testing.T) {
+	defer func(c Clock) { clock = c }(clock)
+	clock = func() time.Time {
+		return time.Date(2018, time.March, 5, 17, 30, 0, 0, time.FixedZone("UTC+1", 3600))
+	}
+	comparePQL(t,
+		"Range(row=10, field='collaboration', start='2018-03-04T16:30', end='2018-03-05T16:30')",
+		collabField.RangeLast(10, 24*time.Hour))
+	comparePQL(t,
+		"Range(row='foo', field='collaboration', start='2018-03-05T16:15', end='2018-03-05T16:30')",
+		collabField.RangeLastK("foo", 15*time.Minute))
+}
+
+func TestRangeDate(t *testing.T) {
+	field, err := sampleIndex.Field("range-date-field", OptFieldTime(TimeQuantumYearMonthDay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2018, time.March, 5, 17, 30, 0, 0, time.UTC)
+	comparePQL(t,
+		"Range(row=10, field='range-date-field', start='2018-03-05T00:00', end='2018-03-05T23:59')",
+		field.RangeDate(10, date))
+	// the date is converted to UTC before truncating
+	date = time.Date(2018, time.March, 5, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	comparePQL(t,
+		"Range(row=10, field='range-date-field', start='2018-03-06T00:00', end='2018-03-06T23:59')",
+		field.RangeDate(10, date))
+
+	field, err = sampleIndex.Field("range-date-hour-field", OptFieldTime(TimeQuantumHour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.RangeDate(10, date).Error() != nil {
+		t.Fatalf("hour quantum should be accepted")
+	}
+	field, err = sampleIndex.Field("range-date-month-field", OptFieldTime(TimeQuantumYearMonth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.RangeDate(10, date).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if sampleField.RangeDate(10, date).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestRangeByDate(t *testing.T) {
+	comparePQL(t,
+		"Range(row=10, field='collaboration', start='2017-04-24T00:00', end='2017-05-02T23:59')",
+		collabField.RangeByDate(10, 2017, 4, 24, 2017, 5, 2))
+	comparePQL(t,
+		"Range(row=10, field='collaboration', start='2016-02-29T00:00', end='2016-02-29T23:59')",
+		collabField.RangeByDate(10, 2016, 2, 29, 2016, 2, 29))
+	comparePQL(t,
+		"Range(row='foo', field='collaboration', start='2017-12-31T00:00', end='2018-01-01T23:59')",
+		collabField.RangeByDateK("foo", 2017, 12, 31, 2018, 1, 1))
+	invalid := [][6]int{
+		{2017, 4, 25, 2017, 4, 24},
+		{2017, 0, 1, 2017, 4, 24},
+		{2017, 13, 1, 2017, 4, 24},
+		{2017, 2, 29, 2017, 4, 24},
+		{2017, 4, 0, 2017, 4, 24},
+		{2017, 4, 1, 2017, 4, 31},
+	}
+	for _, d := range invalid {
+		if collabField.RangeByDate(10, d[0], d[1], d[2], d[3], d[4], d[5]).Error() == nil {
+			t.Fatalf("%v: should have failed", d)
+		}
+		if collabField.RangeByDateK("foo", d[0], d[1], d[2], d[3], d[4], d[5]).Error() == nil {
+			t.Fatalf("%v: K variant should have failed", d)
+		}
+	}
+}
+
+func TestRangeMonth(t *testing.T) {
+	field, err := sampleIndex.Field("range-month-field", OptFieldTime(TimeQuantumYearMonth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t,
+		"Range(row=10, field='range-month-field', start='2018-02-01T00:00', end='2018-02-28T23:59')",
+		field.RangeMonth(10, 2018, time.February))
+	comparePQL(t,
+		"Range(row=10, field='range-month-field', start='2016-02-01T00:00', end='2016-02-29T23:59')",
+		field.RangeMonth(10, 2016, time.February))
+	comparePQL(t,
+		"Range(row=10, field='range-month-field', start='2018-12-01T00:00', end='2018-12-31T23:59')",
+		field.RangeMonth(10, 2018, time.December))
+
+	field, err = sampleIndex.Field("range-month-year-field", OptFieldTime(TimeQuantumYear))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.RangeMonth(10, 2018, time.February).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestRangeHour(t *testing.T) {
+	field, err := sampleIndex.Field("range-hour-field", OptFieldTime(TimeQuantumDayHour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2018, time.March, 5, 17, 30, 45, 0, time.FixedZone("UTC+1", 3600))
+	comparePQL(t,
+		"Range(row=10, field='range-hour-field', start='2018-03-05T16:00', end='2018-03-05T16:59')",
+		field.RangeHour(10, ts))
+	field, err = sampleIndex.Field("range-hour-day-field", OptFieldTime(TimeQuantumYearMonthDay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.RangeHour(10, ts).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+	if sampleField.RangeHour(10, ts).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
+func TestRangeYear(t *testing.T) {
+	field, err := sampleIndex.Field("range-year-field", OptFieldTime(TimeQuantumYear))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePQL(t,
+		"Range(row=10, field='range-year-field', start='2018-01-01T00:00', end='2018-12-31T23:59')",
+		field.RangeYear(10, 2018))
+	if sampleField.RangeYear(10, 2018).Error() == nil {
+		t.Fatalf("Should have failed")
+	}
+}
+
 func TestIntFieldOptionsToString(t *testing.T) {
 	field, err := sampleIndex.Field("int-field", OptFieldInt(-10, 100))
 	if err != nil {
@@ -610,6 +2463,307 @@ func TestTimeFieldOptionsToString(t *testing.T) {
 	if sortedString(targetString) != sortedString(jsonString) {
 		t.Fatalf("`%s` != `%s`", targetString, jsonString)
 	}
+	if strings.Contains(jsonString, "noStandardView") {
+		t.Fatalf("noStandardView should not be set: %s", jsonString)
+	}
+}
+
+func TestTimeFieldNoStandardViewOptionsToString(t *testing.T) {
+	field, err := sampleIndex.Field("time-field-nsv", OptFieldTimeNoStandardView(TimeQuantumDayHour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonString := field.options.String()
+	targetString := `{"options":{"type":"time","timeQuantum":"DH","noStandardView":true}}`
+	if sortedString(targetString) != sortedString(jsonString) {
+		t.Fatalf("`%s` != `%s`", targetString, jsonString)
+	}
+}
+
+func TestFieldOptionsUnmarshalJSON(t *testing.T) {
+	optionsList := []*FieldOptions{
+		{},
+		{fieldType: FieldTypeSet, cacheType: CacheTypeLRU, cacheSize: 1000},
+		{fieldType: FieldTypeInt, min: -10, max: 100},
+		{fieldType: FieldTypeTime, timeQuantum: TimeQuantumYearMonthDay},
+		{fieldType: FieldTypeTime, timeQuantum: TimeQuantumYearMonthDay, noStandardView: true},
+	}
+	for _, options := range optionsList {
+		decoded := &FieldOptions{}
+		if err := json.Unmarshal([]byte(options.String()), decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !options.Equal(decoded) {
+			t.Fatalf("%v != %v", options, decoded)
+		}
+	}
+}
+
+func TestFieldOptionsUnmarshalJSONInvalid(t *testing.T) {
+	decoded := &FieldOptions{}
+	if err := json.Unmarshal([]byte(`{"options":{"min":"foo"}}`), decoded); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestFieldOptionsClearDefaults(t *testing.T) {
+	field, err := sampleIndex.Field("clear-defaults-set", OptFieldSet(CacheTypeLRU, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !field.options.ClearDefaults().Equal(field.options) {
+		t.Fatalf("explicitly set options should be kept: %v", field.options.ClearDefaults())
+	}
+
+	options := &FieldOptions{}
+	if err := options.addOptions(TimeQuantumYearMonthDay); err != nil {
+		t.Fatal(err)
+	}
+	options.cacheType = CacheTypeRanked
+	options.cacheSize = 50000
+	target := &FieldOptions{timeQuantum: TimeQuantumYearMonthDay}
+	cleared := options.ClearDefaults()
+	if !target.Equal(cleared) {
+		t.Fatalf("%v != %v", target, cleared)
+	}
+	if options.cacheType != CacheTypeRanked {
+		t.Fatalf("ClearDefaults should not modify the original options")
+	}
+
+	decoded := &FieldOptions{}
+	if err := json.Unmarshal([]byte(`{"options":{"type":"int","min":0,"max":10}}`), decoded); err != nil {
+		t.Fatal(err)
+	}
+	target = &FieldOptions{fieldType: FieldTypeInt, min: 0, max: 10}
+	if !target.Equal(decoded.ClearDefaults()) {
+		t.Fatalf("%v != %v", target, decoded.ClearDefaults())
+	}
+}
+
+func TestFieldOptionsExplicitlySet(t *testing.T) {
+	tests := []struct {
+		options []interface{}
+		names   []string
+	}{
+		{nil, []string{}},
+		{[]interface{}{OptFieldSet(CacheTypeLRU, 1000)}, []string{"type", "cacheType", "cacheSize"}},
+		{[]interface{}{OptFieldInt(0, 10)}, []string{"type", "min", "max"}},
+		{[]interface{}{OptFieldTime(TimeQuantumYearMonthDay)}, []string{"type", "timeQuantum"}},
+		{[]interface{}{OptFieldTimeNoStandardView(TimeQuantumYearMonthDay)}, []string{"type", "timeQuantum", "noStandardView"}},
+		{[]interface{}{TimeQuantumDayHour}, []string{"timeQuantum"}},
+	}
+	for i, test := range tests {
+		options := &FieldOptions{}
+		if err := options.addOptions(test.options...); err != nil {
+			t.Fatal(err)
+		}
+		if names := options.ExplicitlySet(); !reflect.DeepEqual(test.names, names) {
+			t.Fatalf("%d: %v != %v", i, test.names, names)
+		}
+	}
+
+	decoded := &FieldOptions{}
+	if err := json.Unmarshal([]byte(`{"options":{"type":"set","cacheSize":100}}`), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if names := decoded.ExplicitlySet(); !reflect.DeepEqual([]string{"type", "cacheSize"}, names) {
+		t.Fatalf("wrong names for decoded options: %v", names)
+	}
+}
+
+func TestTimeQuantumDuration(t *testing.T) {
+	targets := map[TimeQuantum]time.Duration{
+		TimeQuantumYear:             8760 * time.Hour,
+		TimeQuantumMonth:            720 * time.Hour,
+		TimeQuantumDay:              24 * time.Hour,
+		TimeQuantumHour:             time.Hour,
+		TimeQuantumYearMonth:        720 * time.Hour,
+		TimeQuantumMonthDay:         24 * time.Hour,
+		TimeQuantumDayHour:          time.Hour,
+		TimeQuantumYearMonthDay:     24 * time.Hour,
+		TimeQuantumMonthDayHour:     time.Hour,
+		TimeQuantumYearMonthDayHour: time.Hour,
+	}
+	for quantum, target := range targets {
+		d, err := quantum.Duration()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d != target {
+			t.Fatalf("%s: %s != %s", quantum, target, d)
+		}
+	}
+	if _, err := TimeQuantumNone.Duration(); err != ErrNoTimeQuantum {
+		t.Fatalf("expected ErrNoTimeQuantum, got %v", err)
+	}
+	if _, err := TimeQuantum("X").Duration(); err == nil {
+		t.Fatalf("invalid time quantum should fail")
+	}
+}
+
+type testOptionApplier struct {
+	applied int
+}
+
+func (o *testOptionApplier) Apply(options *FieldOptions) error {
+	o.applied++
+	return OptFieldInt(-5, 5)(options)
+}
+
+func (o *testOptionApplier) Name() string {
+	return "test"
+}
+
+type failingOptionApplier struct{}
+
+func (failingOptionApplier) Apply(options *FieldOptions) error {
+	return ErrInvalidFieldOption
+}
+
+func (failingOptionApplier) Name() string {
+	return "failing"
+}
+
+func TestFieldOptionApplier(t *testing.T) {
+	custom := &testOptionApplier{}
+	field, err := sampleIndex.Field("custom-option-field", custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if custom.applied != 1 {
+		t.Fatalf("custom option should be applied once, applied %d times", custom.applied)
+	}
+	if field.options.fieldType != FieldTypeInt || field.options.min != -5 || field.options.max != 5 {
+		t.Fatalf("custom option was not applied: %v", field.options)
+	}
+	if _, err := sampleIndex.Field("failing-option-field", failingOptionApplier{}); err != ErrInvalidFieldOption {
+		t.Fatalf("expected ErrInvalidFieldOption, got %v", err)
+	}
+
+	tests := []struct {
+		applier FieldOptionApplier
+		option  FieldOption
+		name    string
+	}{
+		{FieldOptionSet{CacheTypeLRU, 1000}, OptFieldSet(CacheTypeLRU, 1000), "OptFieldSet"},
+		{FieldOptionInt{-10, 10}, OptFieldInt(-10, 10), "OptFieldInt"},
+		{FieldOptionTime{TimeQuantumYearMonth}, OptFieldTime(TimeQuantumYearMonth), "OptFieldTime"},
+		{FieldOptionTimeNoStandardView{TimeQuantumDay}, OptFieldTimeNoStandardView(TimeQuantumDay), "OptFieldTimeNoStandardView"},
+		{FieldOptionTimestampTruncate{TimeQuantumHour}, OptTimestampTruncate(TimeQuantumHour), "OptTimestampTruncate"},
+	}
+	for _, test := range tests {
+		if test.applier.Name() != test.name {
+			t.Fatalf("%s != %s", test.name, test.applier.Name())
+		}
+		applied := &FieldOptions{}
+		if err := applied.addOptions(test.applier); err != nil {
+			t.Fatal(err)
+		}
+		target := &FieldOptions{}
+		if err := target.addOptions(test.option); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(target, applied) {
+			t.Fatalf("%s: %v != %v", test.name, target, applied)
+		}
+	}
+	if err := (FieldOptionInt{10, -10}).Apply(&FieldOptions{}); err != ErrInvalidFieldOption {
+		t.Fatalf("expected ErrInvalidFieldOption, got %v", err)
+	}
+}
+
+func TestOptTimestampTruncate(t *testing.T) {
+	ts := time.Date(2017, time.April, 24, 12, 14, 30, 0, time.UTC)
+	targets := map[TimeQuantum]string{
+		TimeQuantumYear:         "2017-01-01T00:00",
+		TimeQuantumYearMonth:    "2017-04-01T00:00",
+		TimeQuantumYearMonthDay: "2017-04-24T00:00",
+		TimeQuantumDayHour:      "2017-04-24T12:00",
+	}
+	for quantum, target := range targets {
+		field, err := sampleIndex.Field(fmt.Sprintf("timestamp-truncate-%s", strings.ToLower(string(quantum))),
+			OptFieldTime(TimeQuantumYearMonthDayHour), OptTimestampTruncate(quantum))
+		if err != nil {
+			t.Fatal(err)
+		}
+		comparePQL(t,
+			fmt.Sprintf("SetBit(row=1, field='%s', col=2, timestamp='%s')", field.Name(), target),
+			field.SetBitTimestamp(1, 2, ts))
+		comparePQL(t,
+			fmt.Sprintf("SetBit(row='a', field='%s', col='b', timestamp='%s')", field.Name(), target),
+			field.SetBitTimestampK("a", "b", ts))
+	}
+	comparePQL(t,
+		"SetBit(row=1, field='sample-field', col=2, timestamp='2017-04-24T12:14')",
+		sampleField.SetBitTimestamp(1, 2, ts))
+	if _, err := sampleIndex.Field("timestamp-truncate-invalid", OptTimestampTruncate(TimeQuantumNone)); err != ErrInvalidFieldOption {
+		t.Fatalf("expected ErrInvalidFieldOption, got %v", err)
+	}
+}
+
+func TestTruncateToQuantum(t *testing.T) {
+	ts := time.Date(2017, time.April, 24, 12, 14, 30, 0, time.UTC)
+	targets := map[TimeQuantum]time.Time{
+		TimeQuantumYear:         time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+		TimeQuantumYearMonth:    time.Date(2017, time.April, 1, 0, 0, 0, 0, time.UTC),
+		TimeQuantumYearMonthDay: time.Date(2017, time.April, 24, 0, 0, 0, 0, time.UTC),
+		TimeQuantumDayHour:      time.Date(2017, time.April, 24, 12, 0, 0, 0, time.UTC),
+	}
+	for quantum, target := range targets {
+		field, err := sampleIndex.Field(fmt.Sprintf("truncate-%s", strings.ToLower(string(quantum))), OptFieldTime(quantum))
+		if err != nil {
+			t.Fatal(err)
+		}
+		truncated, err := field.TruncateToQuantum(ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !truncated.Equal(target) {
+			t.Fatalf("%s: %s != %s", quantum, target, truncated)
+		}
+	}
+	if _, err := sampleField.TruncateToQuantum(ts); err != ErrNoTimeQuantum {
+		t.Fatalf("expected ErrNoTimeQuantum, got %v", err)
+	}
+}
+
+func TestFieldTypeHelpers(t *testing.T) {
+	tests := []struct {
+		fieldType FieldType
+		isSet     bool
+		isInteger bool
+		isTime    bool
+	}{
+		{FieldTypeDefault, false, false, false},
+		{FieldTypeSet, true, false, false},
+		{FieldTypeInt, false, true, false},
+		{FieldTypeTime, false, false, true},
+		{FieldType("unknown"), false, false, false},
+	}
+	for _, test := range tests {
+		if test.fieldType.IsSet() != test.isSet {
+			t.Fatalf("%q: IsSet should be %v", test.fieldType, test.isSet)
+		}
+		if test.fieldType.IsInteger() != test.isInteger {
+			t.Fatalf("%q: IsInteger should be %v", test.fieldType, test.isInteger)
+		}
+		if test.fieldType.IsTime() != test.isTime {
+			t.Fatalf("%q: IsTime should be %v", test.fieldType, test.isTime)
+		}
+	}
+}
+
+func TestFieldOptionsEqual(t *testing.T) {
+	options := &FieldOptions{fieldType: FieldTypeInt, min: -10, max: 100}
+	if !options.Equal(&FieldOptions{fieldType: FieldTypeInt, min: -10, max: 100}) {
+		t.Fatalf("options should be equal")
+	}
+	if options.Equal(&FieldOptions{fieldType: FieldTypeInt, min: -10, max: 101}) {
+		t.Fatalf("options should not be equal")
+	}
+	if options.Equal(nil) {
+		t.Fatalf("options should not be equal to nil")
+	}
 }
 
 func TestInvalidFieldOption(t *testing.T) {
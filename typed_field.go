@@ -0,0 +1,374 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+package pilosa
+
+import "time"
+
+// IntField is a field which stores integer values.
+// It exposes only the operations which are valid for integer fields.
+type IntField struct {
+	field *Field
+}
+
+// IntField creates an integer field with the given name and value range.
+func (idx *Index) IntField(name string, min int64, max int64) (*IntField, error) {
+	field, err := idx.Field(name, OptFieldInt(min, max))
+	if err != nil {
+		return nil, err
+	}
+	return &IntField{field: field}, nil
+}
+
+// Field returns the underlying field.
+func (f *IntField) Field() *Field {
+	return f.field
+}
+
+// Name returns the name of the field.
+func (f *IntField) Name() string {
+	return f.field.Name()
+}
+
+// LT creates a less than query.
+func (f *IntField) LT(n int64) *PQLRowQuery {
+	return f.field.LT(n)
+}
+
+// LTE creates a less than or equal query.
+func (f *IntField) LTE(n int64) *PQLRowQuery {
+	return f.field.LTE(n)
+}
+
+// GT creates a greater than query.
+func (f *IntField) GT(n int64) *PQLRowQuery {
+	return f.field.GT(n)
+}
+
+// GTE creates a greater than or equal query.
+func (f *IntField) GTE(n int64) *PQLRowQuery {
+	return f.field.GTE(n)
+}
+
+// Equals creates an equals query.
+func (f *IntField) Equals(n int64) *PQLRowQuery {
+	return f.field.Equals(n)
+}
+
+// NotEquals creates a not equals query.
+func (f *IntField) NotEquals(n int64) *PQLRowQuery {
+	return f.field.NotEquals(n)
+}
+
+// NotNull creates a not equal to null query.
+func (f *IntField) NotNull() *PQLRowQuery {
+	return f.field.NotNull()
+}
+
+// Between creates a between query.
+func (f *IntField) Between(a int64, b int64) *PQLRowQuery {
+	return f.field.Between(a, b)
+}
+
+// Sum creates a sum query.
+func (f *IntField) Sum(row *PQLRowQuery) *PQLBaseQuery {
+	return f.field.Sum(row)
+}
+
+// Min creates a min query.
+func (f *IntField) Min(row *PQLRowQuery) *PQLBaseQuery {
+	return f.field.Min(row)
+}
+
+// Max creates a max query.
+func (f *IntField) Max(row *PQLRowQuery) *PQLBaseQuery {
+	return f.field.Max(row)
+}
+
+// SetIntValue creates a SetValue query.
+func (f *IntField) SetIntValue(columnID uint64, value int64) *PQLBaseQuery {
+	return f.field.SetIntValue(columnID, value)
+}
+
+// SetIntValueK creates a SetValue query using a string column key.
+// This will only work against a Pilosa Enterprise server.
+func (f *IntField) SetIntValueK(columnKey string, value int64) *PQLBaseQuery {
+	return f.field.SetIntValueK(columnKey, value)
+}
+
+// TimeField is a field which stores bits with timestamps.
+// It exposes only the operations which are valid for time fields.
+type TimeField struct {
+	field *Field
+}
+
+// TimeField creates a time field with the given name and time quantum.
+func (idx *Index) TimeField(name string, quantum TimeQuantum) (*TimeField, error) {
+	field, err := idx.Field(name, OptFieldTime(quantum))
+	if err != nil {
+		return nil, err
+	}
+	return &TimeField{field: field}, nil
+}
+
+// Field returns the underlying field.
+func (f *TimeField) Field() *Field {
+	return f.field
+}
+
+// Name returns the name of the field.
+func (f *TimeField) Name() string {
+	return f.field.Name()
+}
+
+// Row creates a Row query.
+func (f *TimeField) Row(rowID uint64) *PQLRowQuery {
+	return f.field.Row(rowID)
+}
+
+// RowK creates a Row query using a string row key.
+// This will only work against a Pilosa Enterprise server.
+func (f *TimeField) RowK(rowKey string) *PQLRowQuery {
+	return f.field.RowK(rowKey)
+}
+
+// SetBit creates a SetBit query.
+func (f *TimeField) SetBit(rowID uint64, columnID uint64) *PQLBaseQuery {
+	return f.field.SetBit(rowID, columnID)
+}
+
+// SetBitK creates a SetBit query using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (f *TimeField) SetBitK(rowKey string, columnKey string) *PQLBaseQuery {
+	return f.field.SetBitK(rowKey, columnKey)
+}
+
+// SetBitTimestamp creates a SetBit query with timestamp.
+func (f *TimeField) SetBitTimestamp(rowID uint64, columnID uint64, timestamp time.Time) *PQLBaseQuery {
+	return f.field.SetBitTimestamp(rowID, columnID, timestamp)
+}
+
+// SetBitTimestampK creates a SetBit query with timestamp using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (f *TimeField) SetBitTimestampK(rowKey string, columnKey string, timestamp time.Time) *PQLBaseQuery {
+	return f.field.SetBitTimestampK(rowKey, columnKey, timestamp)
+}
+
+// ClearBit creates a ClearBit query.
+func (f *TimeField) ClearBit(rowID uint64, columnID uint64) *PQLBaseQuery {
+	return f.field.ClearBit(rowID, columnID)
+}
+
+// ClearBitK creates a ClearBit query using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (f *TimeField) ClearBitK(rowKey string, columnKey string) *PQLBaseQuery {
+	return f.field.ClearBitK(rowKey, columnKey)
+}
+
+// Range creates a Range query.
+func (f *TimeField) Range(rowID uint64, start time.Time, end time.Time) *PQLRowQuery {
+	return f.field.Range(rowID, start, end)
+}
+
+// RangeK creates a Range query using a string row key.
+// This will only work against a Pilosa Enterprise server.
+func (f *TimeField) RangeK(rowKey string, start time.Time, end time.Time) *PQLRowQuery {
+	return f.field.RangeK(rowKey, start, end)
+}
+
+// RangeLast creates a Range query which covers the duration d until now, in UTC.
+func (f *TimeField) RangeLast(rowID uint64, d time.Duration) *PQLRowQuery {
+	return f.field.RangeLast(rowID, d)
+}
+
+// RangeLastK creates a Range query using a string row key which covers the duration d until now, in UTC.
+// This will only work against a Pilosa Enterprise server.
+func (f *TimeField) RangeLastK(rowKey string, d time.Duration) *PQLRowQuery {
+	return f.field.RangeLastK(rowKey, d)
+}
+
+// RangeDate creates a Range query which covers the given day in UTC.
+func (f *TimeField) RangeDate(rowID uint64, date time.Time) *PQLRowQuery {
+	return f.field.RangeDate(rowID, date)
+}
+
+// RangeHour creates a Range query which covers the hour containing t in UTC.
+func (f *TimeField) RangeHour(rowID uint64, t time.Time) *PQLRowQuery {
+	return f.field.RangeHour(rowID, t)
+}
+
+// RangeMonth creates a Range query which covers the given month in UTC.
+func (f *TimeField) RangeMonth(rowID uint64, year int, month time.Month) *PQLRowQuery {
+	return f.field.RangeMonth(rowID, year, month)
+}
+
+// RangeYear creates a Range query which covers the given year in UTC.
+func (f *TimeField) RangeYear(rowID uint64, year int) *PQLRowQuery {
+	return f.field.RangeYear(rowID, year)
+}
+
+// SetField is a field which stores bits.
+// It exposes only the operations which are valid for set fields.
+type SetField struct {
+	field *Field
+}
+
+// SetField creates a set field with the given name and the default cache options.
+func (idx *Index) SetField(name string) (*SetField, error) {
+	field, err := idx.Field(name, OptFieldSet(CacheTypeDefault, CacheSizeDefault))
+	if err != nil {
+		return nil, err
+	}
+	return &SetField{field: field}, nil
+}
+
+// Field returns the underlying field.
+func (f *SetField) Field() *Field {
+	return f.field
+}
+
+// Name returns the name of the field.
+func (f *SetField) Name() string {
+	return f.field.Name()
+}
+
+// Row creates a Row query.
+func (f *SetField) Row(rowID uint64) *PQLRowQuery {
+	return f.field.Row(rowID)
+}
+
+// RowK creates a Row query using a string row key.
+// This will only work against a Pilosa Enterprise server.
+func (f *SetField) RowK(rowKey string) *PQLRowQuery {
+	return f.field.RowK(rowKey)
+}
+
+// SetBit creates a SetBit query.
+func (f *SetField) SetBit(rowID uint64, columnID uint64) *PQLBaseQuery {
+	return f.field.SetBit(rowID, columnID)
+}
+
+// SetBitK creates a SetBit query using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (f *SetField) SetBitK(rowKey string, columnKey string) *PQLBaseQuery {
+	return f.field.SetBitK(rowKey, columnKey)
+}
+
+// ClearBit creates a ClearBit query.
+func (f *SetField) ClearBit(rowID uint64, columnID uint64) *PQLBaseQuery {
+	return f.field.ClearBit(rowID, columnID)
+}
+
+// ClearBitK creates a ClearBit query using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (f *SetField) ClearBitK(rowKey string, columnKey string) *PQLBaseQuery {
+	return f.field.ClearBitK(rowKey, columnKey)
+}
+
+// SetBitRange creates a batch query which sets the bits of the row for columns from startCol to endCol, inclusive.
+func (f *SetField) SetBitRange(rowID uint64, startCol uint64, endCol uint64) *PQLBatchQuery {
+	return f.field.SetBitRange(rowID, startCol, endCol)
+}
+
+// ClearRow creates a ClearRow query.
+func (f *SetField) ClearRow(rowID uint64) *PQLBaseQuery {
+	return f.field.ClearRow(rowID)
+}
+
+// ClearRowK creates a ClearRow query using a string row key.
+// This will only work against a Pilosa Enterprise server.
+func (f *SetField) ClearRowK(rowKey string) *PQLBaseQuery {
+	return f.field.ClearRowK(rowKey)
+}
+
+// IncludesColumn creates a query which counts whether the column is set in the row.
+func (f *SetField) IncludesColumn(rowID uint64, columnID uint64) *PQLBaseQuery {
+	return f.field.IncludesColumn(rowID, columnID)
+}
+
+// IncludesColumnK creates an IncludesColumn query using string row and column keys.
+// This will only work against a Pilosa Enterprise server.
+func (f *SetField) IncludesColumnK(rowKey string, columnKey string) *PQLBaseQuery {
+	return f.field.IncludesColumnK(rowKey, columnKey)
+}
+
+// TopN creates a TopN query with the given item count.
+//
+// Deprecated: Use TopNFiltered with a nil row instead.
+func (f *SetField) TopN(n uint64) *PQLRowQuery {
+	return f.field.TopNFiltered(n, nil)
+}
+
+// RowTopN creates a TopN query with the given item count and row.
+//
+// Deprecated: Use TopNFiltered instead.
+func (f *SetField) RowTopN(n uint64, row *PQLRowQuery) *PQLRowQuery {
+	return f.field.TopNFiltered(n, row)
+}
+
+// TopNFiltered creates a TopN query with the given item count and row.
+// Pass nil for the row to count all columns.
+func (f *SetField) TopNFiltered(n uint64, row *PQLRowQuery) *PQLRowQuery {
+	return f.field.TopNFiltered(n, row)
+}
+
+// TopNThreshold creates a TopN query with the given item count and threshold.
+func (f *SetField) TopNThreshold(n uint64, threshold uint64) *PQLRowQuery {
+	return f.field.TopNThreshold(n, threshold)
+}
+
+// TopNPaged creates a TopN query which returns enough rows for the page with the given index.
+func (f *SetField) TopNPaged(pageSize uint64, pageIndex uint64) *PQLRowQuery {
+	return f.field.TopNPaged(pageSize, pageIndex)
+}
+
+// FilterFieldTopN creates a TopN query with the given item count, row, field and the filter for that field.
+func (f *SetField) FilterFieldTopN(n uint64, row *PQLRowQuery, field string, values ...interface{}) *PQLRowQuery {
+	return f.field.FilterFieldTopN(n, row, field, values...)
+}
+
+// FilterFieldTopNK creates a TopN query with the given item count, row key, field and the filter for that field.
+// This will only work against a Pilosa Enterprise server.
+func (f *SetField) FilterFieldTopNK(n uint64, rowKey string, field string, values ...interface{}) *PQLRowQuery {
+	return f.field.FilterFieldTopNK(n, rowKey, field, values...)
+}
+
+// SetRowAttrs creates a SetRowAttrs query.
+func (f *SetField) SetRowAttrs(rowID uint64, attrs map[string]interface{}) *PQLBaseQuery {
+	return f.field.SetRowAttrs(rowID, attrs)
+}
+
+// SetRowAttrsK creates a SetRowAttrs query using a string row key.
+// This will only work against a Pilosa Enterprise server.
+func (f *SetField) SetRowAttrsK(rowKey string, attrs map[string]interface{}) *PQLBaseQuery {
+	return f.field.SetRowAttrsK(rowKey, attrs)
+}
@@ -0,0 +1,155 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+package pilosa
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntField(t *testing.T) {
+	index, err := NewIndex("typed-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := index.IntField("price", -1000, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.Name() != "price" {
+		t.Fatalf("calling field.Name should return field's name")
+	}
+	if field.Field().options.fieldType != FieldTypeInt || field.Field().options.min != -1000 || field.Field().options.max != 1000 {
+		t.Fatalf("field options were not set: %v", field.Field().options)
+	}
+	comparePQL(t, "Range(price < 10)", field.LT(10))
+	comparePQL(t, "Range(price <= 10)", field.LTE(10))
+	comparePQL(t, "Range(price > 10)", field.GT(10))
+	comparePQL(t, "Range(price >= 10)", field.GTE(10))
+	comparePQL(t, "Range(price == 10)", field.Equals(10))
+	comparePQL(t, "Range(price != 10)", field.NotEquals(10))
+	comparePQL(t, "Range(price != null)", field.NotNull())
+	comparePQL(t, "Range(price >< [10,20])", field.Between(10, 20))
+	comparePQL(t, "Sum(field='price')", field.Sum(nil))
+	comparePQL(t, "Min(field='price')", field.Min(nil))
+	comparePQL(t, "Max(field='price')", field.Max(nil))
+	comparePQL(t, "SetValue(col=5, price=10)", field.SetIntValue(5, 10))
+	comparePQL(t, "SetValue(col='mycol', price=10)", field.SetIntValueK("mycol", 10))
+
+	if _, err := index.IntField("invalid-int", 10, 9); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestTimeField(t *testing.T) {
+	index, err := NewIndex("typed-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := index.TimeField("event", TimeQuantumYearMonthDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.Name() != "event" {
+		t.Fatalf("calling field.Name should return field's name")
+	}
+	if field.Field().options.fieldType != FieldTypeTime || field.Field().options.timeQuantum != TimeQuantumYearMonthDay {
+		t.Fatalf("field options were not set: %v", field.Field().options)
+	}
+	timestamp := time.Date(2017, time.April, 24, 12, 14, 0, 0, time.UTC)
+	comparePQL(t, "Bitmap(row=1, field='event')", field.Row(1))
+	comparePQL(t, "Bitmap(row='foo', field='event')", field.RowK("foo"))
+	comparePQL(t, "SetBit(row=1, field='event', col=2)", field.SetBit(1, 2))
+	comparePQL(t, "SetBit(row='foo', field='event', col='bar')", field.SetBitK("foo", "bar"))
+	comparePQL(t, "SetBit(row=1, field='event', col=2, timestamp='2017-04-24T12:14')",
+		field.SetBitTimestamp(1, 2, timestamp))
+	comparePQL(t, "SetBit(row='foo', field='event', col='bar', timestamp='2017-04-24T12:14')",
+		field.SetBitTimestampK("foo", "bar", timestamp))
+	comparePQL(t, "ClearBit(row=1, field='event', col=2)", field.ClearBit(1, 2))
+	comparePQL(t, "ClearBit(row='foo', field='event', col='bar')", field.ClearBitK("foo", "bar"))
+	comparePQL(t, "Range(row=1, field='event', start='2017-04-24T12:14', end='2017-04-24T12:14')",
+		field.Range(1, timestamp, timestamp))
+	comparePQL(t, "Range(row='foo', field='event', start='2017-04-24T12:14', end='2017-04-24T12:14')",
+		field.RangeK("foo", timestamp, timestamp))
+	comparePQL(t, "Range(row=1, field='event', start='2017-04-24T00:00', end='2017-04-24T23:59')",
+		field.RangeDate(1, timestamp))
+	comparePQL(t, "Range(row=1, field='event', start='2017-04-01T00:00', end='2017-04-30T23:59')",
+		field.RangeMonth(1, 2017, time.April))
+	comparePQL(t, "Range(row=1, field='event', start='2017-01-01T00:00', end='2017-12-31T23:59')",
+		field.RangeYear(1, 2017))
+}
+
+func TestSetField(t *testing.T) {
+	index, err := NewIndex("typed-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := index.SetField("stargazer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.Name() != "stargazer" {
+		t.Fatalf("calling field.Name should return field's name")
+	}
+	if field.Field().options.fieldType != FieldTypeSet {
+		t.Fatalf("field options were not set: %v", field.Field().options)
+	}
+	attrs := map[string]interface{}{"active": true}
+	comparePQL(t, "Bitmap(row=1, field='stargazer')", field.Row(1))
+	comparePQL(t, "Bitmap(row='foo', field='stargazer')", field.RowK("foo"))
+	comparePQL(t, "SetBit(row=1, field='stargazer', col=2)", field.SetBit(1, 2))
+	comparePQL(t, "SetBit(row='foo', field='stargazer', col='bar')", field.SetBitK("foo", "bar"))
+	comparePQL(t, "ClearBit(row=1, field='stargazer', col=2)", field.ClearBit(1, 2))
+	comparePQL(t, "ClearBit(row='foo', field='stargazer', col='bar')", field.ClearBitK("foo", "bar"))
+	comparePQL(t, "SetBit(row=1, field='stargazer', col=2)SetBit(row=1, field='stargazer', col=3)", field.SetBitRange(1, 2, 3))
+	comparePQL(t, "ClearRow(row=1, field='stargazer')", field.ClearRow(1))
+	comparePQL(t, "ClearRow(row='foo', field='stargazer')", field.ClearRowK("foo"))
+	comparePQL(t, "Count(Intersect(Bitmap(row=1, field='stargazer'), Bitmap(col=2)))", field.IncludesColumn(1, 2))
+	comparePQL(t, "Count(Intersect(Bitmap(row='foo', field='stargazer'), Bitmap(col='bar')))", field.IncludesColumnK("foo", "bar"))
+	comparePQL(t, "TopN(field='stargazer', n=5)", field.TopN(5))
+	comparePQL(t, "TopN(Bitmap(row=1, field='stargazer'), field='stargazer', n=5)", field.RowTopN(5, field.Row(1)))
+	comparePQL(t, "TopN(field='stargazer', n=5)", field.TopNFiltered(5, nil))
+	comparePQL(t, "TopN(Bitmap(row=1, field='stargazer'), field='stargazer', n=5)", field.TopNFiltered(5, field.Row(1)))
+	comparePQL(t, "TopN(field='stargazer', n=5, treshold=3)", field.TopNThreshold(5, 3))
+	comparePQL(t, "TopN(field='stargazer', n=10)", field.TopNPaged(5, 1))
+	comparePQL(t, "TopN(Bitmap(row=1, field='stargazer'), field='stargazer', n=5, field='category', filters=[80])",
+		field.FilterFieldTopN(5, field.Row(1), "category", 80))
+	comparePQL(t, "TopN(Bitmap(row='foo', field='stargazer'), field='stargazer', n=5, field='category', filters=[80])",
+		field.FilterFieldTopNK(5, "foo", "category", 80))
+	comparePQL(t, "SetRowAttrs(row=1, field='stargazer', active=true)", field.SetRowAttrs(1, attrs))
+	comparePQL(t, "SetRowAttrs(row='foo', field='stargazer', active=true)", field.SetRowAttrsK("foo", attrs))
+
+	if _, err := index.SetField("$invalid$"); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
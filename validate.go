@@ -34,11 +34,16 @@ package pilosa
 
 import (
 	"regexp"
+
+	"github.com/pkg/errors"
 )
 
+// MaxNameLength is the maximum length of index and field names.
+const MaxNameLength = 64
+
 const (
-	maxIndexName = 64
-	maxFieldName = 64
+	maxIndexName = MaxNameLength
+	maxFieldName = MaxNameLength
 	maxLabel     = 64
 	maxKey       = 64
 )
@@ -68,20 +73,43 @@ func ValidKey(key string) bool {
 	return len(key) <= maxKey && keyRegex.Match([]byte(key))
 }
 
-func validateIndexName(name string) error {
+// ValidateIndexName returns an error if the given index name is not valid, otherwise nil.
+// A valid index name starts with a lowercase letter, contains only lowercase letters,
+// digits, hyphens (-) and underscores (_), and is at most MaxNameLength characters long.
+// Names which are too long cause an error wrapping ErrNameTooLong,
+// other invalid names cause ErrInvalidIndexName.
+func ValidateIndexName(name string) error {
+	if err := validateNameLength(name); err != nil {
+		return err
+	}
 	if ValidIndexName(name) {
 		return nil
 	}
 	return ErrInvalidIndexName
 }
 
-func validateFieldName(name string) error {
+// ValidateFieldName returns an error if the given field name is not valid, otherwise nil.
+// A valid field name starts with a lowercase letter, contains only lowercase letters,
+// digits, hyphens (-) and underscores (_), and is at most MaxNameLength characters long.
+// Names which are too long cause an error wrapping ErrNameTooLong,
+// other invalid names cause ErrInvalidFieldName.
+func ValidateFieldName(name string) error {
+	if err := validateNameLength(name); err != nil {
+		return err
+	}
 	if ValidFieldName(name) {
 		return nil
 	}
 	return ErrInvalidFieldName
 }
 
+func validateNameLength(name string) error {
+	if len(name) > MaxNameLength {
+		return errors.Wrapf(ErrNameTooLong, "name has %d characters, maximum is %d", len(name), MaxNameLength)
+	}
+	return nil
+}
+
 func validateLabel(label string) error {
 	if ValidLabel(label) {
 		return nil
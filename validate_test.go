@@ -32,7 +32,12 @@
 
 package pilosa
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
 
 func TestValidateIndexName(t *testing.T) {
 	names := []string{
@@ -40,7 +45,7 @@ func TestValidateIndexName(t *testing.T) {
 		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
 	}
 	for _, name := range names {
-		if validateIndexName(name) != nil {
+		if ValidateIndexName(name) != nil {
 			t.Fatalf("Should be valid index name: %s", name)
 		}
 	}
@@ -52,7 +57,7 @@ func TestValidateIndexNameInvalid(t *testing.T) {
 		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1",
 	}
 	for _, name := range names {
-		if validateIndexName(name) == nil {
+		if ValidateIndexName(name) == nil {
 			t.Fatalf("Should be invalid index name: %s", name)
 		}
 	}
@@ -64,7 +69,7 @@ func TestValidateFieldName(t *testing.T) {
 		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
 	}
 	for _, name := range names {
-		if validateFieldName(name) != nil {
+		if ValidateFieldName(name) != nil {
 			t.Fatalf("Should be valid field name: %s", name)
 		}
 	}
@@ -76,13 +81,45 @@ func TestValidateFieldNameInvalid(t *testing.T) {
 		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1",
 	}
 	for _, name := range names {
-		if validateFieldName(name) == nil {
+		if ValidateFieldName(name) == nil {
 			t.Fatalf("Should be invalid field name: %s", name)
 		}
 	}
 
 }
 
+func TestValidateNameErrors(t *testing.T) {
+	for _, name := range []string{"", "1abc"} {
+		if err := ValidateIndexName(name); err != ErrInvalidIndexName {
+			t.Fatalf("Should be ErrInvalidIndexName for %s: %v", name, err)
+		}
+		if err := ValidateFieldName(name); err != ErrInvalidFieldName {
+			t.Fatalf("Should be ErrInvalidFieldName for %s: %v", name, err)
+		}
+	}
+}
+
+func TestValidateNameLength(t *testing.T) {
+	for _, length := range []int{63, 64} {
+		name := "a" + strings.Repeat("b", length-1)
+		if err := ValidateIndexName(name); err != nil {
+			t.Fatalf("Should be valid index name with %d characters: %v", length, err)
+		}
+		if err := ValidateFieldName(name); err != nil {
+			t.Fatalf("Should be valid field name with %d characters: %v", length, err)
+		}
+	}
+	name := "a" + strings.Repeat("b", 64)
+	for _, err := range []error{ValidateIndexName(name), ValidateFieldName(name)} {
+		if errors.Cause(err) != ErrNameTooLong {
+			t.Fatalf("Should be ErrNameTooLong: %v", err)
+		}
+		if !strings.Contains(err.Error(), "65") {
+			t.Fatalf("Error should contain the name length: %v", err)
+		}
+	}
+}
+
 func TestValidateLabel(t *testing.T) {
 	labels := []string{
 		"a", "ab", "ab1", "d_e", "A", "Bc", "B1", "aB", "b-c",
@@ -0,0 +1,265 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+package pilosa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PQLCall is a call in a parsed PQL query, such as Bitmap(row=1, field='f').
+// Args contains the arguments which are not calls, e.g., row=1, in the order they appear.
+// Children contains the arguments which are calls.
+type PQLCall struct {
+	Name     string
+	Args     []string
+	Children []*PQLCall
+}
+
+// PQLVisitor is called by Walk for each call in a query.
+// VisitRow is called for the calls which return rows, such as Bitmap, Union or TopN,
+// i.e., the calls which are created as PQLRowQuery by this package.
+// VisitBase is called for the other calls.
+// VisitBatch is called once for a batch query, before its calls are visited.
+type PQLVisitor interface {
+	VisitRow(call *PQLCall)
+	VisitBase(call *PQLCall)
+	VisitBatch(query *PQLBatchQuery)
+}
+
+// rowCalls are the names of the calls which are created as PQLRowQuery.
+var rowCalls = map[string]bool{
+	"All":        true,
+	"Bitmap":     true,
+	"Difference": true,
+	"Intersect":  true,
+	"Not":        true,
+	"Range":      true,
+	"TopN":       true,
+	"Union":      true,
+	"Xor":        true,
+}
+
+// Walk parses the PQL of the query and calls the visitor for each call in it, depth first.
+// It returns the error of the query if it has one, or ErrInvalidPQL if the PQL cannot be parsed.
+// In that case the visitor is not called.
+func Walk(query PQLQuery, visitor PQLVisitor) error {
+	if err := query.Error(); err != nil {
+		return err
+	}
+	calls, err := parsePQL(query.serialize())
+	if err != nil {
+		return err
+	}
+	if batch, ok := query.(*PQLBatchQuery); ok {
+		visitor.VisitBatch(batch)
+	}
+	for _, call := range calls {
+		walkCall(call, visitor)
+	}
+	return nil
+}
+
+func walkCall(call *PQLCall, visitor PQLVisitor) {
+	if rowCalls[call.Name] {
+		visitor.VisitRow(call)
+	} else {
+		visitor.VisitBase(call)
+	}
+	for _, child := range call.Children {
+		walkCall(child, visitor)
+	}
+}
+
+// CountingVisitor is a PQLVisitor which counts the calls in a query.
+type CountingVisitor struct {
+	Rows    int
+	Bases   int
+	Batches int
+}
+
+// VisitRow increments the row call count.
+func (v *CountingVisitor) VisitRow(call *PQLCall) {
+	v.Rows++
+}
+
+// VisitBase increments the base call count.
+func (v *CountingVisitor) VisitBase(call *PQLCall) {
+	v.Bases++
+}
+
+// VisitBatch increments the batch count.
+func (v *CountingVisitor) VisitBatch(query *PQLBatchQuery) {
+	v.Batches++
+}
+
+// Total returns the number of visited calls.
+func (v *CountingVisitor) Total() int {
+	return v.Rows + v.Bases
+}
+
+// pqlParser is a minimal PQL parser which splits a query into calls and their arguments.
+// Arguments which are not calls are not parsed further.
+type pqlParser struct {
+	pql string
+	pos int
+}
+
+func parsePQL(pql string) ([]*PQLCall, error) {
+	p := &pqlParser{pql: pql}
+	calls := []*PQLCall{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.pql) {
+			return calls, nil
+		}
+		call, err := p.parseCall()
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+}
+
+func (p *pqlParser) parseCall() (*PQLCall, error) {
+	start := p.pos
+	for p.pos < len(p.pql) && isPQLNameChar(p.pql[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start || p.pos >= len(p.pql) || p.pql[p.pos] != '(' {
+		return nil, p.errorf("expected a call")
+	}
+	call := &PQLCall{Name: p.pql[start:p.pos]}
+	p.pos++
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.pql) {
+			return nil, p.errorf("unterminated call %s", call.Name)
+		}
+		if p.pql[p.pos] == ')' {
+			p.pos++
+			return call, nil
+		}
+		if p.atCall() {
+			child, err := p.parseCall()
+			if err != nil {
+				return nil, err
+			}
+			call.Children = append(call.Children, child)
+		} else {
+			arg, err := p.parseArg()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+		}
+		p.skipSpace()
+		if p.pos < len(p.pql) {
+			switch p.pql[p.pos] {
+			case ',':
+				p.pos++
+			case ')':
+			default:
+				return nil, p.errorf("expected , or )")
+			}
+		}
+	}
+}
+
+// parseArg reads an argument until the next top level comma or closing parenthesis.
+func (p *pqlParser) parseArg() (string, error) {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.pql) {
+		switch c := p.pql[p.pos]; c {
+		case '\'', '"':
+			if err := p.skipQuoted(c); err != nil {
+				return "", err
+			}
+			continue
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ',', ')':
+			if depth == 0 {
+				return strings.TrimSpace(p.pql[start:p.pos]), nil
+			}
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated argument")
+}
+
+func (p *pqlParser) skipQuoted(quote byte) error {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.pql) {
+		switch p.pql[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case quote:
+			p.pos++
+			return nil
+		}
+		p.pos++
+	}
+	p.pos = start
+	return p.errorf("unterminated string")
+}
+
+// atCall returns true if a call starts at the current position.
+func (p *pqlParser) atCall() bool {
+	i := p.pos
+	for i < len(p.pql) && isPQLNameChar(p.pql[i]) {
+		i++
+	}
+	return i > p.pos && i < len(p.pql) && p.pql[i] == '('
+}
+
+func (p *pqlParser) skipSpace() {
+	for p.pos < len(p.pql) && strings.IndexByte(" \t\r\n", p.pql[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *pqlParser) errorf(format string, args ...interface{}) error {
+	return errors.Wrapf(ErrInvalidPQL, "%s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func isPQLNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
@@ -0,0 +1,138 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived
+// from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR
+// CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+// WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH
+// DAMAGE.
+
+package pilosa
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestWalkCounts(t *testing.T) {
+	tests := []struct {
+		query   PQLQuery
+		rows    int
+		bases   int
+		batches int
+	}{
+		{b1, 1, 0, 0},
+		{sampleIndex.Union(b1, b2, b3), 4, 0, 0},
+		{sampleIndex.Count(sampleIndex.Intersect(b1, sampleIndex.Not(b2))), 4, 1, 0},
+		{sampleField.TopNFiltered(5, b1), 2, 0, 0},
+		{sampleField.Sum(b1), 1, 1, 0},
+		{sampleField.SetRowAttrs(1, map[string]interface{}{"quote": "a)b,'c"}), 0, 1, 0},
+		{sampleIndex.BatchQuery(b1, sampleField.SetBit(1, 2), sampleIndex.Count(b2)), 2, 2, 1},
+		{sampleIndex.BatchQuery(), 0, 0, 1},
+		{Noop, 0, 0, 0},
+	}
+	for i, test := range tests {
+		v := &CountingVisitor{}
+		if err := Walk(test.query, v); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if v.Rows != test.rows || v.Bases != test.bases || v.Batches != test.batches {
+			t.Fatalf("%d: expected %d rows, %d bases, %d batches; got %#v", i, test.rows, test.bases, test.batches, v)
+		}
+		if v.Total() != test.rows+test.bases {
+			t.Fatalf("%d: wrong total %d", i, v.Total())
+		}
+	}
+}
+
+type recordingVisitor struct {
+	calls []*PQLCall
+}
+
+func (v *recordingVisitor) VisitRow(call *PQLCall)          { v.calls = append(v.calls, call) }
+func (v *recordingVisitor) VisitBase(call *PQLCall)         { v.calls = append(v.calls, call) }
+func (v *recordingVisitor) VisitBatch(query *PQLBatchQuery) {}
+
+func TestWalkCalls(t *testing.T) {
+	v := &recordingVisitor{}
+	q := sampleIndex.Count(sampleIndex.Union(sampleField.RowK("it's"), sampleField.FilterFieldTopN(5, b1, "category", 80, 81)))
+	if err := Walk(q, v); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, call := range v.calls {
+		names = append(names, call.Name)
+	}
+	if !reflect.DeepEqual([]string{"Count", "Union", "Bitmap", "TopN", "Bitmap"}, names) {
+		t.Fatalf("wrong call order: %v", names)
+	}
+	if !reflect.DeepEqual([]string{`row='it\'s'`, "field='sample-field'"}, v.calls[2].Args) {
+		t.Fatalf("wrong args: %#v", v.calls[2].Args)
+	}
+	if !reflect.DeepEqual([]string{"field='sample-field'", "n=5", "field='category'", "filters=[80,81]"}, v.calls[3].Args) {
+		t.Fatalf("wrong args: %#v", v.calls[3].Args)
+	}
+}
+
+func TestWalkObjectArgs(t *testing.T) {
+	v := &recordingVisitor{}
+	q := NewPQLBaseQuery(`SetRowAttrs(row=1, field='f', {"a":1,"b":2})`, sampleIndex, nil)
+	if err := Walk(q, v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.calls) != 1 {
+		t.Fatalf("wrong calls: %v", v.calls)
+	}
+	if !reflect.DeepEqual([]string{"row=1", "field='f'", `{"a":1,"b":2}`}, v.calls[0].Args) {
+		t.Fatalf("wrong args: %#v", v.calls[0].Args)
+	}
+}
+
+func TestWalkErrors(t *testing.T) {
+	invalid := []string{
+		"Bitmap(",
+		"Bitmap(row=1",
+		"Bitmap(row='1)",
+		"Count(Bitmap(row=1) x)",
+		"row=1",
+		")",
+	}
+	for _, pql := range invalid {
+		v := &CountingVisitor{}
+		err := Walk(sampleIndex.RawQuery(pql), v)
+		if errors.Cause(err) != ErrInvalidPQL {
+			t.Fatalf("%s: expected ErrInvalidPQL, got %v", pql, err)
+		}
+		if v.Total() != 0 {
+			t.Fatalf("%s: visitor should not be called", pql)
+		}
+	}
+	if err := Walk(sampleIndex.Xor(b1), &CountingVisitor{}); err == nil {
+		t.Fatalf("should have returned the query error")
+	}
+}